@@ -5,12 +5,20 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/deadc0de6/checkah/internal/alert"
 	"github.com/deadc0de6/checkah/internal/config"
+	"github.com/deadc0de6/checkah/internal/history"
 	"github.com/deadc0de6/checkah/internal/output"
 	"github.com/deadc0de6/checkah/internal/remote"
+	"github.com/deadc0de6/checkah/internal/silence"
+	"github.com/deadc0de6/checkah/internal/state"
+	"github.com/deadc0de6/checkah/internal/transport"
 
 	"github.com/docopt/docopt-go"
 	"github.com/fatih/color"
@@ -22,32 +30,74 @@ type Switches struct {
 	// actions
 	Print   bool `docopt:"print"`
 	Check   bool `docopt:"check"`
+	Watch   bool `docopt:"watch"`
 	Example bool `docopt:"example"`
+	Silence bool `docopt:"silence"`
+	Ack     bool `docopt:"ack"`
+	Add     bool `docopt:"add"`
+	List    bool `docopt:"list"`
+	Del     bool `docopt:"del"`
 	// args
-	Paths []string `docopt:"<path>"`
+	Paths     []string `docopt:"<path>"`
+	ID        string   `docopt:"<id>"`
+	Host      string   `docopt:"<host>"`
+	CheckName string   `docopt:"<check>"`
 	// options
-	Local   bool   `docopt:"-l,--local"`
-	Format  string `docopt:"-f,--format"`
-	Verbose bool   `docopt:"-v,--verbose"`
-	Version bool   `docopt:"--version"`
-	Help    bool   `docopt:"-h,--help"`
+	Local    bool     `docopt:"-l,--local"`
+	Format   string   `docopt:"-f,--format"`
+	StateDir string   `docopt:"--state-dir"`
+	Hosts    []string `docopt:"--host"`
+	Tags     []string `docopt:"--tag"`
+	Checks   []string `docopt:"--check"`
+	All      bool     `docopt:"--all"`
+	For      string   `docopt:"--for"`
+	Reason   string   `docopt:"--reason"`
+	Comment  string   `docopt:"--comment"`
+	Output   string   `docopt:"-o,--output"`
+	Interval string   `docopt:"--interval"`
+	Verbose  bool     `docopt:"-v,--verbose"`
+	Version  bool     `docopt:"--version"`
+	Help     bool     `docopt:"-h,--help"`
 }
 
+const (
+	spoolFile     = "spool.json"
+	rateLimitFile = "ratelimit.json"
+	silencesFile  = "silences.json"
+	historyFile   = "history.json"
+	acksFile      = "acks.json"
+)
+
 var (
 	version = "0.3.4"
 	name    = "checkah"
 	usage   = `checkah.
 
 Usage:
-	checkah check [-v] <path>...
+	checkah check [-v] [--output=<output>] <path>...
+	checkah watch [-v] [--output=<output>] [--interval=<interval>] <path>...
 	checkah print [-v] [--format=<format>] <path>...
 	checkah example [-lv] [--format=<format>]
+	checkah silence add [-v] [--state-dir=<dir>] [--host=<host>...] [--tag=<tag>...] [--check=<check>...] [--all] [--for=<duration>] [--reason=<reason>] [<path>...]
+	checkah silence list [-v] [--state-dir=<dir>] [<path>...]
+	checkah silence del [-v] [--state-dir=<dir>] <id> [<path>...]
+	checkah ack [-v] [--state-dir=<dir>] [--comment=<comment>] <host> <check> [<path>...]
 	checkah -h | --help
 	checkah --version
 
 Options:
   -l --local              Generate localhost config example.
   -f --format=<format>    Output format [default: yaml].
+  -o --output=<output>    Check output (stdout, json, syslog, journald) [default: stdout].
+  --interval=<interval>   Time between two runs [default: 5m].
+  --state-dir=<dir>       The state directory.
+  --host=<host>           Silence this host (glob).
+  --tag=<tag>             Silence hosts with this tag (glob).
+  --check=<check>         Silence this check name or description (glob).
+  --all                   Silence all hosts and checks.
+  --for=<duration>        Silence duration [default: 1h].
+  --reason=<reason>       Why it is silenced.
+  --comment=<comment>     Acknowledgement comment.
   -v --verbose            Debug logs.
   -h --help               Show this screen.
   --version               Show version.`
@@ -67,7 +117,21 @@ func cmdExample(format string, local bool) int {
 }
 
 func cmdPrint(configs []string, format string) int {
-	cfg, _, err := parseConfigs(configs)
+	cfg, err := parseConfigs(configs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// validate
+	_, err = toRemotes(cfg, nil, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = remote.GetGlobalAlerts(cfg, nil, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = silence.FromMaintenance(cfg.Maintenance)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -80,35 +144,109 @@ func cmdPrint(configs []string, format string) int {
 	return 0
 }
 
-// returns total hosts, total checks, hosts error, nbError
-func cmdCheck(configs []string) (int, int, int, int) {
-	cfg, remotes, err := parseConfigs(configs)
+// runStats the counts of a run
+type runStats struct {
+	hosts    int
+	checks   int
+	hostErr  int
+	errCnt   int
+	mutedCnt int
+}
+
+// cmdCheck checks all the hosts once, the SSH
+// connections are taken from the pool if not nil
+func cmdCheck(configs []string, outputName string, pool *transport.Pool) (*runStats, error) {
+	cfg, err := parseConfigs(configs)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+
+	spool, err := alert.NewSpool(state.Path(cfg.Settings.StateDir, spoolFile))
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := alert.NewRateLimits(state.Path(cfg.Settings.StateDir, rateLimitFile))
+	if err != nil {
+		return nil, err
+	}
+
+	remotes, err := toRemotes(cfg, spool, limits)
+	if err != nil {
+		return nil, err
+	}
+	// the alerts are recreated on each run of watch, what
+	// they keep open is closed once the run is over
+	defer closeAlerts(remote.GetAlerts(remotes))
+
+	// maintenance windows and ad-hoc silences
+	rules, err := silence.FromMaintenance(cfg.Maintenance)
+	if err != nil {
+		return nil, err
+	}
+	store, err := silence.Load(state.Path(cfg.Settings.StateDir, silencesFile))
+	if err != nil {
+		return nil, err
+	}
+	rules = append(rules, store.Rules...)
+	silences := silence.New(rules, time.Now())
+
+	hist, err := history.Load(state.Path(cfg.Settings.StateDir, historyFile))
+	if err != nil {
+		return nil, err
+	}
+	acks, err := history.LoadAcks(state.Path(cfg.Settings.StateDir, acksFile))
+	if err != nil {
+		return nil, err
 	}
 
 	hostsParallel := cfg.Settings.HostsParallel
 	checksParallel := cfg.Settings.ChecksParallel
-	globalAlert, _ := alert.GetAlert(cfg.Settings.GlobalAlert.Type, cfg.Settings.GlobalAlert.Options)
+	globalAlerts, err := remote.GetGlobalAlerts(cfg, spool, limits)
+	if err != nil {
+		return nil, err
+	}
+	defer closeAlerts(globalAlerts)
 
 	log.Debugf("hosts parallel: %t", hostsParallel)
 	log.Debugf("checks parallel: %t", checksParallel)
-	log.Debugf("global alert: %v", globalAlert)
+	log.Debugf("global alerts: %d", len(globalAlerts))
 
-	var wg sync.WaitGroup
-	ch := make(chan *remote.HostResult, len(remotes))
+	flushTimeout, err := strconv.Atoi(cfg.Settings.AlertFlush)
+	if err != nil {
+		return nil, err
+	}
 
 	// create the output
-	out, _ := output.GetOutput("stdout", nil)
+	out, err := output.GetOutput(outputName, cfg.Settings.OutputOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+
+	// notifications are delivered in the background
+	disp := alert.NewDispatcher(cfg.Settings.AlertWorkers, cfg.Settings.AlertQueue, spool)
+
+	// retry the deliveries that failed during the last run
+	alerts := remote.GetAlerts(remotes)
+	alerts = append(alerts, globalAlerts...)
+	spool.Replay(alerts, disp)
+	notifier := remote.NewNotifier(disp, cfg.Settings.FloodThreshold, silences, hist, acks)
+
+	var wg sync.WaitGroup
+	ch := make(chan *remote.HostResult, len(remotes))
 
 	// check all hosts
+	start := time.Now()
+	var results []*remote.HostResult
 	errCnt := 0
 	hostErrCnt := 0
 	checksCnt := 0
+	mutedCnt := 0
 	for _, r := range remotes {
 		wg.Add(1)
 		log.Debugf("launching checks on %s", r.Name)
-		go remote.CheckRemote(r, checksParallel, ch, &wg, out)
+		go remote.CheckRemote(r, checksParallel, ch, &wg, out, notifier, pool)
 		if !hostsParallel {
 			wg.Wait()
 		}
@@ -117,47 +255,168 @@ func cmdCheck(configs []string) (int, int, int, int) {
 	wg.Wait()
 	close(ch)
 
+	// release held notifications
+	notifier.Flush()
+
 	// process results
 	for res := range ch {
-		if res.NbCheckError > 0 {
+		if res.NbCheckError-res.NbCheckMuted > 0 {
 			hostErrCnt++
 		}
 		errCnt += res.NbCheckError
 		checksCnt += res.NbCheckTotal
+		mutedCnt += res.NbCheckMuted
+		results = append(results, res)
 	}
 
-	if globalAlert != nil && errCnt > 0 {
-		line := fmt.Sprintf("check failed: %d/%d host(s) failed (check error: %d)", hostErrCnt, len(remotes), errCnt)
-		err := globalAlert.Notify(line)
+	summary := remote.Summary(results, time.Since(start))
+	if summary != nil {
+		for _, a := range globalAlerts {
+			disp.Dispatch(a, summary)
+		}
+	}
+
+	// wait for all notifications to be sent
+	err = disp.Close(time.Duration(flushTimeout) * time.Second)
+	if err != nil {
+		log.Errorf("%v", err)
+	}
+
+	err = spool.Save()
+	if err != nil {
+		log.Errorf("saving spool: %v", err)
+	}
+	err = limits.Save()
+	if err != nil {
+		log.Errorf("saving rate limits: %v", err)
+	}
+	err = hist.Save()
+	if err != nil {
+		log.Errorf("saving history: %v", err)
+	}
+	err = acks.Save()
+	if err != nil {
+		log.Errorf("saving acks: %v", err)
+	}
+	stats := &runStats{
+		hosts:    len(remotes),
+		checks:   checksCnt,
+		hostErr:  hostErrCnt,
+		errCnt:   errCnt,
+		mutedCnt: mutedCnt,
+	}
+	return stats, nil
+}
+
+// closeAlerts closes the alerts once their notifications are sent
+func closeAlerts(alerts []alert.Alert) {
+	for _, a := range alerts {
+		err := a.Close()
 		if err != nil {
-			log.Errorf("%v", err)
+			log.Debugf("closing %s: %v", a.GetDescription(), err)
 		}
 	}
-	return len(remotes), checksCnt, hostErrCnt, errCnt
 }
 
-func parseConfigs(paths []string) (*config.Config, []*remote.Remote, error) {
+// cmdWatch checks the hosts every interval until interrupted,
+// the SSH connections are kept open between the runs
+func cmdWatch(configs []string, outputName string, interval string) int {
+	every, err := time.ParseDuration(interval)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if every <= 0 {
+		log.Fatalf("bad interval \"%s\"", interval)
+	}
+
+	cfg, err := parseConfigs(configs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	keepalive, err := strconv.Atoi(cfg.Settings.SSHKeepalive)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pool := transport.NewPool(time.Duration(keepalive)*time.Second, cfg.Settings.SSHMaxIdle)
+	defer pool.Close()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	for {
+		// the config is reloaded on each run, a
+		// broken one is reported until it is fixed
+		stats, err := cmdCheck(configs, outputName, pool)
+		if err != nil {
+			log.Errorf("run failed: %v", err)
+		} else {
+			printSummary(stats)
+		}
+		log.Debugf("next run in %s", every)
+		select {
+		case sig := <-sigs:
+			log.Debugf("received %s, exiting", sig)
+			return 0
+		case <-time.After(every):
+		}
+	}
+}
+
+// printSummary prints the results of a run and returns
+// the number of errors that are not silenced
+func printSummary(stats *runStats) int {
+	total := stats.hosts
+	totalChecks := stats.checks
+	hostErr := stats.hostErr
+	errCnt := stats.errCnt
+	mutedCnt := stats.mutedCnt
+	errStr := fmt.Sprintf("%d", errCnt)
+	if errCnt > 0 {
+		red := color.New(color.FgRed).SprintFunc()
+		errStr = red(errCnt)
+	}
+	hostErrStr := fmt.Sprintf("%d", hostErr)
+	if hostErr > 0 {
+		red := color.New(color.FgRed).SprintFunc()
+		hostErrStr = red(hostErr)
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Fprintf(color.Output, "\nChecked %d hosts (%d checks):\n", total, totalChecks)
+	mutedStr := ""
+	if mutedCnt > 0 {
+		yellow := color.New(color.FgYellow).SprintFunc()
+		mutedStr = fmt.Sprintf(", %s muted", yellow(mutedCnt))
+	}
+	fmt.Fprintf(color.Output, "%s success, %s failed (%s total errors%s)\n", green(total-hostErr), hostErrStr, errStr, mutedStr)
+	// silenced, flapping or pending errors do not fail the run
+	return errCnt - mutedCnt
+}
+
+func parseConfigs(paths []string) (*config.Config, error) {
 	c := &config.Config{}
 	for _, path := range paths {
 		cfg, err := config.ReadCfg(path)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		c, err = config.MergeConfigs(c, cfg)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 	}
-	remotes, err := remote.ToRemote(c)
+	return c, nil
+}
+
+func toRemotes(cfg *config.Config, spool *alert.Spool, limits *alert.RateLimits) ([]*remote.Remote, error) {
+	remotes, err := remote.ToRemote(cfg, spool, limits)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	if log.GetLevel() == log.DebugLevel {
 		remote.PrintRemotes(remotes)
 	}
 
-	return c, remotes, nil
+	return remotes, nil
 }
 
 func main() {
@@ -181,7 +440,12 @@ func main() {
 		printUsage()
 	}
 
-	fmt.Printf("%s v%s\n", name, version)
+	// keep stdout parsable for the json output
+	if opts.Output == "json" {
+		color.Output = os.Stderr
+	}
+
+	fmt.Fprintf(color.Output, "%s v%s\n", name, version)
 	if opts.Version {
 		os.Exit(0)
 	}
@@ -195,26 +459,26 @@ func main() {
 		ret = cmdPrint(paths, opts.Format)
 	} else if opts.Example {
 		ret = cmdExample(opts.Format, opts.Local)
+	} else if opts.Silence {
+		ret = cmdSilence(&opts)
+	} else if opts.Ack {
+		ret = cmdAck(&opts)
 	} else if opts.Check {
 		paths := opts.Paths
 		if len(paths) < 1 {
 			printUsage()
 		}
-		total, totalChecks, hostErr, errCnt := cmdCheck(paths)
-		ret = errCnt
-		errStr := fmt.Sprintf("%d", errCnt)
-		if errCnt > 0 {
-			red := color.New(color.FgRed).SprintFunc()
-			errStr = red(errCnt)
+		stats, err := cmdCheck(paths, opts.Output, nil)
+		if err != nil {
+			log.Fatal(err)
 		}
-		hostErrStr := fmt.Sprintf("%d", hostErr)
-		if hostErr > 0 {
-			red := color.New(color.FgRed).SprintFunc()
-			hostErrStr = red(hostErr)
+		ret = printSummary(stats)
+	} else if opts.Watch {
+		paths := opts.Paths
+		if len(paths) < 1 {
+			printUsage()
 		}
-		green := color.New(color.FgGreen).SprintFunc()
-		fmt.Printf("\nChecked %d hosts (%d checks):\n", total, totalChecks)
-		fmt.Printf("%s success, %s failed (%s total errors)\n", green(total-hostErr), hostErrStr, errStr)
+		ret = cmdWatch(paths, opts.Output, opts.Interval)
 	}
 
 	if ret != 0 {
@@ -0,0 +1,142 @@
+// Copyright (c) 2021 deadc0de6
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/deadc0de6/checkah/internal/history"
+	"github.com/deadc0de6/checkah/internal/silence"
+	"github.com/deadc0de6/checkah/internal/state"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func printSilence(r *silence.Rule) {
+	fmt.Printf("%s: until %s", r.ID, r.End.Format("2006-01-02 15:04:05"))
+	if len(r.Hosts)+len(r.Tags)+len(r.Checks) < 1 {
+		fmt.Printf(" all hosts and checks")
+	}
+	if len(r.Hosts) > 0 {
+		fmt.Printf(" host(s): %s", strings.Join(r.Hosts, ","))
+	}
+	if len(r.Tags) > 0 {
+		fmt.Printf(" tag(s): %s", strings.Join(r.Tags, ","))
+	}
+	if len(r.Checks) > 0 {
+		fmt.Printf(" check(s): %s", strings.Join(r.Checks, ","))
+	}
+	if len(r.Reason) > 0 {
+		fmt.Printf(" reason: %s", r.Reason)
+	}
+	fmt.Println()
+}
+
+// stateDir returns the state directory of the configs if
+// any, it must then be the same as the --state-dir one
+func stateDir(opts *Switches) string {
+	if len(opts.Paths) < 1 {
+		return opts.StateDir
+	}
+	cfg, err := parseConfigs(opts.Paths)
+	if err != nil {
+		log.Fatal(err)
+	}
+	dir := cfg.Settings.StateDir
+	if len(opts.StateDir) > 0 && filepath.Clean(opts.StateDir) != filepath.Clean(dir) {
+		log.Fatalf("--state-dir \"%s\" differs from the config state-dir \"%s\"", opts.StateDir, dir)
+	}
+	return dir
+}
+
+func cmdSilence(opts *Switches) int {
+	store, err := silence.Load(state.Path(stateDir(opts), silencesFile))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if opts.List {
+		now := time.Now()
+		for _, r := range store.Rules {
+			if r.Active(now) {
+				printSilence(r)
+			}
+		}
+		return 0
+	}
+
+	if opts.Add {
+		if len(opts.Hosts)+len(opts.Tags)+len(opts.Checks) < 1 && !opts.All {
+			log.Fatal("no --host, --tag or --check, use --all to silence everything")
+		}
+		duration, err := time.ParseDuration(opts.For)
+		if err != nil {
+			log.Fatal(err)
+		}
+		now := time.Now()
+		r := &silence.Rule{
+			Hosts:  opts.Hosts,
+			Tags:   opts.Tags,
+			Checks: opts.Checks,
+			Start:  now,
+			End:    now.Add(duration),
+			Reason: opts.Reason,
+		}
+		store.Add(r)
+		printSilence(r)
+	} else if opts.Del {
+		err = store.Remove(opts.ID)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err = store.Save()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return 0
+}
+
+func cmdAck(opts *Switches) int {
+	dir := stateDir(opts)
+	acks, err := history.LoadAcks(state.Path(dir, acksFile))
+	if err != nil {
+		log.Fatal(err)
+	}
+	hist, err := history.Load(state.Path(dir, historyFile))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// only the checks failing now are acknowledged
+	failing := hist.Failing(opts.Host, opts.CheckName)
+	if len(failing) < 1 {
+		log.Fatalf("no failing check matching \"%s\" on \"%s\"", opts.CheckName, opts.Host)
+	}
+	var added []*history.Ack
+	for _, r := range failing {
+		ack := &history.Ack{
+			Host:    r.Host,
+			Check:   r.Description,
+			Comment: opts.Comment,
+			User:    os.Getenv("USER"),
+			Time:    time.Now(),
+		}
+		acks.Add(ack)
+		added = append(added, ack)
+	}
+
+	err = acks.Save()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, ack := range added {
+		fmt.Printf("%s on %s %s (until it recovers)\n", ack.Check, ack.Host, ack.String())
+	}
+	return 0
+}
@@ -7,6 +7,7 @@ require (
 	github.com/docopt/docopt-go v0.0.0-20180111231733-ee0de3bc6815
 	github.com/fatih/color v1.12.0
 	github.com/influxdata/influxdb-client-go/v2 v2.5.1
+	github.com/pkg/sftp v1.13.9
 	github.com/sirupsen/logrus v1.8.1
 	github.com/spf13/viper v1.8.1
 	golang.org/x/crypto v0.48.0
@@ -21,6 +22,7 @@ require (
 	github.com/fsnotify/fsnotify v1.4.9 // indirect
 	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/influxdata/line-protocol v0.0.0-20200327222509-2487e7298839 // indirect
+	github.com/kr/fs v0.1.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/magiconair/properties v1.8.5 // indirect
 	github.com/mattn/go-colorable v0.1.8 // indirect
@@ -3,15 +3,118 @@
 package alert
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"regexp"
+	"sort"
+	"strings"
+	"time"
 )
 
+// severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// statuses
+const (
+	StatusProblem  = "problem"
+	StatusRecovery = "recovery"
+)
+
+// Event the alert event
+type Event struct {
+	Host        string    `json:"host"`
+	Check       string    `json:"check"`
+	Description string    `json:"description"`
+	Message     string    `json:"message"`
+	Severity    string    `json:"severity"`
+	Status      string    `json:"status"`
+	Tags        []string  `json:"tags,omitempty"`
+	Failures    int       `json:"failures,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// String returns the event as a single line
+func (e *Event) String() string {
+	if len(e.Host) < 1 {
+		return e.Message
+	}
+	if e.Status == StatusRecovery {
+		return fmt.Sprintf("RECOVERED \"%s\" - %s", e.Host, e.Message)
+	}
+	return fmt.Sprintf("ALERT \"%s\" - %s", e.Host, e.Message)
+}
+
+// Title returns a short title for the event
+func (e *Event) Title() string {
+	if len(e.Host) < 1 {
+		return "checkah alert"
+	}
+	if e.Status == StatusRecovery {
+		return fmt.Sprintf("checkah recovery on %s", e.Host)
+	}
+	return fmt.Sprintf("checkah alert on %s", e.Host)
+}
+
+// NewEvent creates a new event
+func NewEvent(host string, check string, description string, message string, severity string) *Event {
+	if len(severity) < 1 {
+		severity = SeverityCritical
+	}
+	e := &Event{
+		Host:        host,
+		Check:       check,
+		Description: description,
+		Message:     message,
+		Severity:    severity,
+		Status:      StatusProblem,
+		Time:        time.Now(),
+	}
+	return e
+}
+
+// ParseSeverity validates a severity string
+func ParseSeverity(severity string) (string, error) {
+	s := strings.ToLower(severity)
+	switch s {
+	case "":
+		return SeverityCritical, nil
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+		return s, nil
+	}
+	return "", fmt.Errorf("no such severity: %s", severity)
+}
+
 // Alert the alert interface
 type Alert interface {
-	Notify(string) error
+	Notify(context.Context, *Event) error
 	GetDescription() string
 	GetOptions() map[string]string
+	// releases what the alert keeps open between notifications
+	Close() error
+}
+
+// Key returns a stable identifier of an alert, from its
+// description and options, to find it again across runs
+func Key(a Alert) string {
+	options := a.GetOptions()
+	var keys []string
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	h.Write([]byte(a.GetDescription()))
+	for _, k := range keys {
+		fmt.Fprintf(h, "\x00%s=%s", k, options[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
 }
 
 // GetAlert returns an alert instance
@@ -27,10 +130,26 @@ func GetAlert(name string, options map[string]string) (Alert, error) {
 		return NewAlertCommand(options)
 	case "email":
 		return NewAlertEmail(options)
+	case "telegram":
+		return NewAlertTelegram(options)
+	case "ntfy":
+		return NewAlertNtfy(options)
+	case "gotify":
+		return NewAlertGotify(options)
+	case "syslog":
+		return NewAlertSyslog(options)
+	case "journald":
+		return NewAlertJournald(options)
 	}
 	return nil, fmt.Errorf("no such alert: %s", name)
 }
 
+// isTrue returns true if an option value is enabled
+func isTrue(value string) bool {
+	v := strings.ToLower(value)
+	return v == "1" || v == "true" || v == "yes"
+}
+
 func splitArgs(args string) []string {
 	r := regexp.MustCompile("'.+'|\".+\"|\\S+")
 	return r.FindAllString(args, -1)
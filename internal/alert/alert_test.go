@@ -0,0 +1,109 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAlert records the events it is notified with
+type fakeAlert struct {
+	desc    string
+	options map[string]string
+	// time a delivery takes
+	delay time.Duration
+	// error returned by the deliveries
+	err    error
+	mut    sync.Mutex
+	events []*Event
+	closed bool
+}
+
+func (a *fakeAlert) Notify(ctx context.Context, e *Event) error {
+	if a.delay > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(a.delay):
+		}
+	}
+	if a.err != nil {
+		return a.err
+	}
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	a.events = append(a.events, e)
+	return nil
+}
+
+func (a *fakeAlert) GetDescription() string {
+	return a.desc
+}
+
+func (a *fakeAlert) GetOptions() map[string]string {
+	return a.options
+}
+
+func (a *fakeAlert) Close() error {
+	a.closed = true
+	return nil
+}
+
+func (a *fakeAlert) count() int {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return len(a.events)
+}
+
+func newFake(desc string, options map[string]string) *fakeAlert {
+	return &fakeAlert{desc: desc, options: options}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a    Alert
+		b    Alert
+		same bool
+	}{
+		{
+			"same alert",
+			newFake("alert to webhook", map[string]string{"url": "http://a", "method": "POST"}),
+			newFake("alert to webhook", map[string]string{"method": "POST", "url": "http://a"}),
+			true,
+		},
+		{
+			"other options",
+			newFake("alert to webhook", map[string]string{"url": "http://a"}),
+			newFake("alert to webhook", map[string]string{"url": "http://b"}),
+			false,
+		},
+		{
+			"other type",
+			newFake("alert to webhook", map[string]string{"url": "http://a"}),
+			newFake("alert to ntfy", map[string]string{"url": "http://a"}),
+			false,
+		},
+		{
+			"an option more",
+			newFake("alert to webhook", map[string]string{"url": "http://a"}),
+			newFake("alert to webhook", map[string]string{"url": "http://a", "method": "PUT"}),
+			false,
+		},
+		{
+			"wrappers keep the key",
+			newFake("alert to file", map[string]string{"path": "/tmp/a"}),
+			NewRouted(NewReliable(newFake("alert to file", map[string]string{"path": "/tmp/a"}), 0, 0, nil, nil), &Route{}),
+			true,
+		},
+	}
+	for _, tt := range tests {
+		got := Key(tt.a) == Key(tt.b)
+		if got != tt.same {
+			t.Errorf("%s: same key = %t, want %t", tt.name, got, tt.same)
+		}
+	}
+}
@@ -3,23 +3,19 @@
 package alert
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
 )
 
 // Command alert file struct
 type Command struct {
-	command string
-	args    []string
+	runner  *runner
 	options map[string]string
 }
 
 // Notify notifies
-func (a *Command) Notify(content string) error {
-	args := append(a.args, fmt.Sprintf("'%s'", content))
-	cmd := exec.Command(a.command, args...)
-	err := cmd.Run()
-	return err
+func (a *Command) Notify(ctx context.Context, e *Event) error {
+	return a.runner.run(ctx, e)
 }
 
 // GetOptions returns this alert options
@@ -29,7 +25,12 @@ func (a *Command) GetOptions() map[string]string {
 
 // GetDescription returns a description for this alert
 func (a *Command) GetDescription() string {
-	return fmt.Sprintf("alert to command %s", a.command)
+	return fmt.Sprintf("alert to command %s", a.runner.command)
+}
+
+// Close does nothing
+func (a *Command) Close() error {
+	return nil
 }
 
 // NewAlertCommand creates a new script alert instance
@@ -43,15 +44,13 @@ func NewAlertCommand(options map[string]string) (*Command, error) {
 		return nil, fmt.Errorf("\"command\" option required")
 	}
 
-	fields := splitArgs(command)
-	var args []string
-	if len(fields) > 1 {
-		args = fields[1:]
+	r, err := newRunner(command, options)
+	if err != nil {
+		return nil, err
 	}
 
 	a := &Command{
-		command: fields[0],
-		args:    args,
+		runner:  r,
 		options: options,
 	}
 	return a, nil
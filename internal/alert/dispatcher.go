@@ -0,0 +1,138 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatih/color"
+	log "github.com/sirupsen/logrus"
+)
+
+// a notification to deliver
+type job struct {
+	alert Alert
+	event *Event
+}
+
+// Dispatcher delivers notifications asynchronously
+// through a bounded pool of workers
+type Dispatcher struct {
+	jobs     chan *job
+	wg       *sync.WaitGroup
+	spool    *Spool
+	ctx      context.Context
+	cancel   context.CancelFunc
+	inflight atomic.Int64
+	stopped  atomic.Bool
+	dropped  atomic.Int64
+}
+
+// giveUp spools a notification that was not delivered
+func (d *Dispatcher) giveUp(j *job) {
+	d.dropped.Add(1)
+	d.spool.Add(j.alert, j.event)
+}
+
+func (d *Dispatcher) deliver(j *job) error {
+	d.inflight.Add(1)
+	defer d.inflight.Add(-1)
+
+	log.Debugf("notify with %s", j.alert.GetDescription())
+	return j.alert.Notify(d.ctx, j.event)
+}
+
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+	for j := range d.jobs {
+		if d.stopped.Load() {
+			d.giveUp(j)
+			continue
+		}
+		err := d.deliver(j)
+		if err != nil {
+			c := fmt.Sprintf("notification error for \"%s\": ", j.alert.GetDescription())
+			col := color.New(color.FgRed)
+			// stderr with the json output
+			fmt.Fprint(color.Output, c+col.Sprintln(err.Error()))
+		}
+	}
+}
+
+// Dispatch queues a notification, blocks if the queue is full,
+// events not routed to the alert are dropped
+func (d *Dispatcher) Dispatch(a Alert, e *Event) {
+	r, ok := a.(*Routed)
+	if !ok {
+		r = NewRouted(a, &Route{})
+	}
+	if !r.Accepts(e) {
+		log.Debugf("event not routed to %s", a.GetDescription())
+		return
+	}
+	d.jobs <- &job{
+		alert: a,
+		event: e,
+	}
+}
+
+// Close waits for all queued notifications to be delivered, after
+// the timeout the queued ones are spooled and the ones being delivered
+// are stopped, they are spooled by the alerts
+func (d *Dispatcher) Close(timeout time.Duration) error {
+	defer d.cancel()
+	close(d.jobs)
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+	}
+
+	// stop delivering and drain the queue
+	d.stopped.Store(true)
+	for j := range d.jobs {
+		d.giveUp(j)
+	}
+
+	// and stop what is being delivered
+	d.dropped.Add(d.inflight.Load())
+	d.cancel()
+	<-done
+
+	return fmt.Errorf("%d notification(s) not delivered after %v", d.dropped.Load(), timeout)
+}
+
+// NewDispatcher creates a dispatcher and starts its workers
+func NewDispatcher(workers int, queueSize int, spool *Spool) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		jobs:   make(chan *job, queueSize),
+		wg:     &sync.WaitGroup{},
+		spool:  spool,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+	return d
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newSpool(t *testing.T) *Spool {
+	t.Helper()
+	s, err := NewSpool(filepath.Join(t.TempDir(), "spool.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	tests := []struct {
+		workers int
+		queue   int
+		events  int
+	}{
+		{1, 0, 5},
+		{1, 10, 5},
+		{4, 2, 20},
+		// bad values fall back to a single worker without queue
+		{0, -1, 3},
+	}
+	for _, tt := range tests {
+		a := newFake("fake", nil)
+		spool := newSpool(t)
+		d := NewDispatcher(tt.workers, tt.queue, spool)
+		for i := 0; i < tt.events; i++ {
+			d.Dispatch(a, NewEvent("web1", "disk", "disk usage", "full", SeverityCritical))
+		}
+		err := d.Close(5 * time.Second)
+		if err != nil {
+			t.Errorf("%d workers, queue %d: %v", tt.workers, tt.queue, err)
+		}
+		if a.count() != tt.events {
+			t.Errorf("%d workers, queue %d: delivered %d, want %d", tt.workers, tt.queue, a.count(), tt.events)
+		}
+		if len(spool.entries) > 0 {
+			t.Errorf("%d workers, queue %d: %d spooled", tt.workers, tt.queue, len(spool.entries))
+		}
+	}
+}
+
+func TestDispatcherRoutes(t *testing.T) {
+	route, err := NewRoute([]string{SeverityCritical}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newFake("fake", nil)
+	d := NewDispatcher(1, 10, newSpool(t))
+	routed := NewRouted(a, route)
+	d.Dispatch(routed, NewEvent("web1", "disk", "disk usage", "full", SeverityCritical))
+	d.Dispatch(routed, NewEvent("web1", "load", "load average", "high", SeverityWarning))
+	err = d.Close(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.count() != 1 {
+		t.Errorf("delivered %d, want 1", a.count())
+	}
+}
+
+func TestDispatcherFlushTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		reliable bool
+		// spooled once each
+		spooled int
+	}{
+		// the dispatcher spools the queued ones only
+		{"plain", false, 2},
+		// the alert spools the one being delivered
+		{"reliable", true, 3},
+	}
+	for _, tt := range tests {
+		spool := newSpool(t)
+		slow := newFake("slow", nil)
+		slow.delay = time.Minute
+		var a Alert = slow
+		if tt.reliable {
+			a = NewReliable(slow, 0, 0, nil, spool)
+		}
+
+		d := NewDispatcher(1, 10, spool)
+		for i := 0; i < 3; i++ {
+			d.Dispatch(a, NewEvent("web1", "disk", "disk usage", "full", SeverityCritical))
+		}
+		// let the worker pick the first one
+		time.Sleep(50 * time.Millisecond)
+
+		start := time.Now()
+		err := d.Close(100 * time.Millisecond)
+		if err == nil {
+			t.Errorf("%s: no error on timeout", tt.name)
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Errorf("%s: the in-flight delivery was not stopped", tt.name)
+		}
+		if len(spool.entries) != tt.spooled {
+			t.Errorf("%s: %d spooled, want %d", tt.name, len(spool.entries), tt.spooled)
+		}
+		if slow.count() != 0 {
+			t.Errorf("%s: %d delivered after the timeout", tt.name, slow.count())
+		}
+	}
+}
+
+func TestReliable(t *testing.T) {
+	failing := errors.New("failing")
+	tests := []struct {
+		name      string
+		err       error
+		retries   int
+		fallback  error
+		wantErr   bool
+		spooled   int
+		delivered int
+	}{
+		{"delivered", nil, 0, nil, false, 0, 1},
+		{"failed", failing, 0, nil, true, 1, 0},
+		{"failed after retries", failing, 1, nil, true, 1, 0},
+		{"fallback", failing, 0, nil, false, 0, 1},
+		{"fallback failed", failing, 0, failing, true, 1, 0},
+	}
+	for _, tt := range tests {
+		spool := newSpool(t)
+		a := newFake("alert", nil)
+		a.err = tt.err
+		var fb *fakeAlert
+		var fallback Alert
+		if tt.name == "fallback" || tt.name == "fallback failed" {
+			fb = newFake("fallback", nil)
+			fb.err = tt.fallback
+			fallback = fb
+		}
+
+		r := NewReliable(a, time.Second, tt.retries, fallback, spool)
+		err := r.Notify(t.Context(), NewEvent("web1", "disk", "disk usage", "full", SeverityCritical))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %t", tt.name, err, tt.wantErr)
+		}
+		if len(spool.entries) != tt.spooled {
+			t.Errorf("%s: %d spooled, want %d", tt.name, len(spool.entries), tt.spooled)
+		}
+		delivered := a.count()
+		if fb != nil {
+			delivered += fb.count()
+		}
+		if delivered != tt.delivered {
+			t.Errorf("%s: delivered %d, want %d", tt.name, delivered, tt.delivered)
+		}
+	}
+}
+
+func TestReliableTimeout(t *testing.T) {
+	spool := newSpool(t)
+	a := newFake("slow", nil)
+	a.delay = time.Minute
+	r := NewReliable(a, 50*time.Millisecond, 0, nil, spool)
+
+	start := time.Now()
+	err := r.Notify(t.Context(), NewEvent("web1", "disk", "disk usage", "full", SeverityCritical))
+	if err == nil {
+		t.Errorf("no error on timeout")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("the delivery was not stopped")
+	}
+	if len(spool.entries) != 1 {
+		t.Errorf("%d spooled, want 1", len(spool.entries))
+	}
+}
@@ -3,48 +3,158 @@
 package alert
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
 	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"text/template"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// email TLS modes
+const (
+	emailTLSAuto     = ""
+	emailTLSNone     = "none"
+	emailTLSStartTLS = "starttls"
+	emailTLSImplicit = "tls"
+	emailDialTimeout = 10 * time.Second
+	emailTimeout     = 60 * time.Second
+	emailSubject     = "[checkah] {{.Severity}}{{if .Host}} {{.Host}}{{end}}{{if .Description}}: {{.Description}}{{end}}"
+)
+
+var (
+	emailHTML = htmltemplate.Must(htmltemplate.New("html").Parse(`<html><body>
+<h3>{{.Title}}</h3>
+<table>
+{{if .Host}}<tr><td><b>host</b></td><td>{{.Host}}</td></tr>{{end}}
+{{if .Description}}<tr><td><b>check</b></td><td>{{.Description}}</td></tr>{{end}}
+<tr><td><b>severity</b></td><td>{{.Severity}}</td></tr>
+<tr><td><b>time</b></td><td>{{.Time.Format "2006-01-02 15:04:05"}}</td></tr>
+</table>
+<pre>{{.Message}}</pre>
+</body></html>
+`))
+)
+
 // Email alert file struct
 type Email struct {
-	host     string
-	port     string
-	mailfrom string
-	mailto   string
-	user     string
-	password string
-	options  map[string]string
+	host      string
+	port      string
+	mailfrom  *mail.Address
+	mailto    []*mail.Address
+	cc        []*mail.Address
+	bcc       []*mail.Address
+	user      string
+	password  string
+	tlsMode   string
+	tlsConfig *tls.Config
+	subject   *template.Template
+	options   map[string]string
 }
 
-func (a *Email) sendAuth(body []byte) error {
-	addr := fmt.Sprintf("%s:%s", a.host, a.port)
-	log.Debugf("email with auth to %s", addr)
-	auth := smtp.PlainAuth("", a.user, a.password, a.host)
-	return smtp.SendMail(addr, auth, a.mailfrom, []string{a.mailto}, body)
+// dial connects to the server, the connection is
+// closed if ctx is done before stop is called
+func (a *Email) dial(ctx context.Context) (c *smtp.Client, stop func() bool, err error) {
+	addr := net.JoinHostPort(a.host, a.port)
+	dialer := &net.Dialer{Timeout: emailDialTimeout}
+
+	var conn net.Conn
+	if a.tlsMode == emailTLSImplicit {
+		log.Debugf("email implicit TLS to %s", addr)
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: a.tlsConfig}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		log.Debugf("email to %s", addr)
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(emailTimeout))
+	stop = context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	c, err = smtp.NewClient(conn, a.host)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, nil, err
+	}
+
+	if a.tlsMode == emailTLSImplicit {
+		return c, stop, nil
+	}
+
+	if a.tlsMode == emailTLSNone {
+		return c, stop, nil
+	}
+
+	ok, _ := c.Extension("STARTTLS")
+	if ok {
+		log.Debugf("email STARTTLS with %s", addr)
+		err = c.StartTLS(a.tlsConfig)
+		if err != nil {
+			stop()
+			c.Close()
+			return nil, nil, err
+		}
+	} else if a.tlsMode == emailTLSStartTLS {
+		stop()
+		c.Close()
+		return nil, nil, fmt.Errorf("%s does not support STARTTLS", addr)
+	}
+	return c, stop, nil
 }
 
-func (a *Email) sendNoAuth(content string) error {
-	addr := fmt.Sprintf("%s:%s", a.host, a.port)
-	log.Debugf("email plain to %s", addr)
-	c, err := smtp.Dial(addr)
+func (a *Email) send(ctx context.Context, msg []byte) error {
+	c, stop, err := a.dial(ctx)
 	if err != nil {
 		return err
 	}
+	defer c.Close()
+	defer stop()
+
+	// authenticate
+	if len(a.user) > 0 && len(a.password) > 0 {
+		log.Debugf("email with auth as %s", a.user)
+		auth := smtp.PlainAuth("", a.user, a.password, a.host)
+		err = c.Auth(auth)
+		if err != nil {
+			return err
+		}
+	}
 
 	// send from
-	err = c.Mail(a.mailfrom)
+	err = c.Mail(a.mailfrom.Address)
 	if err != nil {
 		return err
 	}
 
 	// send to
-	err = c.Rcpt(a.mailto)
-	if err != nil {
-		return err
+	var rcpts []*mail.Address
+	rcpts = append(rcpts, a.mailto...)
+	rcpts = append(rcpts, a.cc...)
+	rcpts = append(rcpts, a.bcc...)
+	for _, rcpt := range rcpts {
+		err = c.Rcpt(rcpt.Address)
+		if err != nil {
+			return err
+		}
 	}
 
 	// send body
@@ -52,7 +162,7 @@ func (a *Email) sendNoAuth(content string) error {
 	if err != nil {
 		return err
 	}
-	_, err = fmt.Fprint(wc, content)
+	_, err = wc.Write(msg)
 	if err != nil {
 		return err
 	}
@@ -62,27 +172,97 @@ func (a *Email) sendNoAuth(content string) error {
 	}
 
 	// and quit
-	err = c.Quit()
-	return err
+	return c.Quit()
 }
 
-// Notify notifies
-func (a *Email) Notify(content string) error {
-	// body
-	var body string
-	body += fmt.Sprintf("From: %s <%s>\r\n", a.mailfrom, a.mailfrom)
-	body += fmt.Sprintf("To: %s\r\n", a.mailto)
-	body += fmt.Sprintf("Subject: checkah %s\r\n", "alert")
-	body += "\r\n"
-	body += content
-	body += "\r\n"
-
-	// send email
-	if len(a.user) > 0 && len(a.password) > 0 {
-		return a.sendAuth([]byte(body))
+func joinAddresses(addrs []*mail.Address) string {
+	var strs []string
+	for _, addr := range addrs {
+		strs = append(strs, addr.String())
+	}
+	return strings.Join(strs, ", ")
+}
+
+func (a *Email) messageID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	domain := "checkah"
+	idx := strings.LastIndex(a.mailfrom.Address, "@")
+	if idx >= 0 {
+		domain = a.mailfrom.Address[idx+1:]
 	}
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b), domain)
+}
 
-	return a.sendNoAuth(body)
+func writePart(w *multipart.Writer, contentType string, content string) error {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", fmt.Sprintf("%s; charset=utf-8", contentType))
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+	pw, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	qw := quotedprintable.NewWriter(pw)
+	_, err = qw.Write([]byte(content))
+	if err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+func (a *Email) message(e *Event) ([]byte, error) {
+	var subject bytes.Buffer
+	err := a.subject.Execute(&subject, e)
+	if err != nil {
+		return nil, err
+	}
+
+	var html bytes.Buffer
+	err = emailHTML.Execute(&html, e)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	err = writePart(w, "text/plain", e.String()+"\r\n")
+	if err != nil {
+		return nil, err
+	}
+	err = writePart(w, "text/html", html.String())
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// headers (bcc is not part of the headers)
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", a.mailfrom.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", joinAddresses(a.mailto))
+	if len(a.cc) > 0 {
+		fmt.Fprintf(&msg, "Cc: %s\r\n", joinAddresses(a.cc))
+	}
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject.String()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", e.Time.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: %s\r\n", a.messageID())
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=\"%s\"\r\n", w.Boundary())
+	fmt.Fprintf(&msg, "\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// Notify notifies
+func (a *Email) Notify(ctx context.Context, e *Event) error {
+	msg, err := a.message(e)
+	if err != nil {
+		return err
+	}
+	return a.send(ctx, msg)
 }
 
 // GetOptions returns this alert options
@@ -92,7 +272,46 @@ func (a *Email) GetOptions() map[string]string {
 
 // GetDescription returns a description for this alert
 func (a *Email) GetDescription() string {
-	return fmt.Sprintf("alert to email %s", a.mailto)
+	return fmt.Sprintf("alert to email %s", joinAddresses(a.mailto))
+}
+
+// Close does nothing
+func (a *Email) Close() error {
+	return nil
+}
+
+func parseAddresses(options map[string]string, key string) ([]*mail.Address, error) {
+	v, ok := options[key]
+	if !ok || len(strings.TrimSpace(v)) < 1 {
+		return nil, nil
+	}
+	addrs, err := mail.ParseAddressList(v)
+	if err != nil {
+		return nil, fmt.Errorf("bad \"%s\" value: %v", key, err)
+	}
+	return addrs, nil
+}
+
+// newTLSConfig returns a TLS config with the "ca" and "insecure_tls" options
+func newTLSConfig(host string, options map[string]string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: isTrue(options["insecure_tls"]),
+	}
+
+	ca, ok := options["ca"]
+	if ok {
+		pem, err := os.ReadFile(ca)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in \"%s\"", ca)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
 }
 
 // NewAlertEmail creates a new file alert instance
@@ -107,34 +326,71 @@ func NewAlertEmail(options map[string]string) (*Email, error) {
 		return nil, fmt.Errorf("\"port\" option required")
 	}
 
-	mailfrom, ok := options["mailfrom"]
+	from, ok := options["mailfrom"]
 	if !ok {
 		return nil, fmt.Errorf("\"mailfrom\" option required")
 	}
+	mailfrom, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("bad \"mailfrom\" value: %v", err)
+	}
 
-	mailto, ok := options["mailto"]
-	if !ok {
+	mailto, err := parseAddresses(options, "mailto")
+	if err != nil {
+		return nil, err
+	}
+	if len(mailto) < 1 {
 		return nil, fmt.Errorf("\"mailto\" option required")
 	}
 
-	user, ok := options["user"]
-	if !ok {
-		user = ""
+	cc, err := parseAddresses(options, "cc")
+	if err != nil {
+		return nil, err
 	}
 
-	password, ok := options["password"]
+	bcc, err := parseAddresses(options, "bcc")
+	if err != nil {
+		return nil, err
+	}
+
+	tlsMode := strings.ToLower(options["tls"])
+	switch tlsMode {
+	case emailTLSAuto:
+		if port == "465" {
+			tlsMode = emailTLSImplicit
+		}
+	case emailTLSNone, emailTLSStartTLS, emailTLSImplicit:
+	default:
+		return nil, fmt.Errorf("bad \"tls\" value: %s", tlsMode)
+	}
+
+	tlsConfig, err := newTLSConfig(host, options)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, ok := options["subject"]
 	if !ok {
-		password = ""
+		subject = emailSubject
+	}
+	tmpl, err := template.New("subject").Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("bad \"subject\" template: %v", err)
 	}
 
 	a := &Email{
-		host:     host,
-		port:     port,
-		mailto:   mailto,
-		mailfrom: mailfrom,
-		user:     user,
-		password: password,
-		options:  options,
+		host:      host,
+		port:      port,
+		mailto:    mailto,
+		cc:        cc,
+		bcc:       bcc,
+		mailfrom:  mailfrom,
+		user:      options["user"],
+		password:  options["password"],
+		tlsMode:   tlsMode,
+		tlsConfig: tlsConfig,
+		subject:   tmpl,
+		options:   options,
 	}
 	return a, nil
 }
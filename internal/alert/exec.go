@@ -0,0 +1,137 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	execTimeout = 30 * time.Second
+	execShell   = "/bin/sh"
+	// output kept for error reporting
+	execOutputMax = 512
+)
+
+// runner runs a local command for an event
+type runner struct {
+	command string
+	args    []string
+	shell   bool
+	timeout time.Duration
+}
+
+// shellQuote quotes a string for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// unquote removes the quotes kept by splitArgs
+func unquote(s string) string {
+	if len(s) > 1 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// eventEnv returns the event as environment variables
+func eventEnv(e *Event) []string {
+	return []string{
+		"CHECKAH_HOST=" + e.Host,
+		"CHECKAH_CHECK=" + e.Check,
+		"CHECKAH_DESCRIPTION=" + e.Description,
+		"CHECKAH_MESSAGE=" + e.Message,
+		"CHECKAH_SEVERITY=" + e.Severity,
+		"CHECKAH_STATUS=" + e.Status,
+		"CHECKAH_FAILURES=" + strconv.Itoa(e.Failures),
+		"CHECKAH_TIME=" + e.Time.Format(time.RFC3339),
+	}
+}
+
+// truncated returns the end of the output
+func truncated(out []byte) string {
+	out = bytes.TrimSpace(out)
+	if len(out) > execOutputMax {
+		out = out[len(out)-execOutputMax:]
+	}
+	return string(out)
+}
+
+// run runs the command with the event line as last argument,
+// the event as CHECKAH_* variables and as JSON on stdin
+func (r *runner) run(parent context.Context, e *Event) error {
+	stdin, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(parent, r.timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if r.shell {
+		line := fmt.Sprintf("%s %s", r.command, shellQuote(e.String()))
+		cmd = exec.CommandContext(ctx, execShell, "-c", line)
+	} else {
+		args := append(append([]string{}, r.args...), e.String())
+		cmd = exec.CommandContext(ctx, r.command, args...)
+	}
+	cmd.Env = append(os.Environ(), eventEnv(e)...)
+	cmd.Stdin = bytes.NewReader(append(stdin, '\n'))
+	// do not wait for children holding the output
+	cmd.WaitDelay = time.Second
+
+	out, err := cmd.CombinedOutput()
+	if parent.Err() != nil {
+		return fmt.Errorf("killed: %v: %s", parent.Err(), truncated(out))
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timeout after %s: %s", r.timeout, truncated(out))
+	}
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, truncated(out))
+	}
+	log.Debugf("%s output: %s", r.command, truncated(out))
+	return nil
+}
+
+// newRunner creates a runner, the command is run through
+// a shell if the "shell" option is set
+func newRunner(command string, options map[string]string) (*runner, error) {
+	r := &runner{
+		command: command,
+		shell:   isTrue(options["shell"]),
+		timeout: execTimeout,
+	}
+
+	timeout, ok := options["timeout"]
+	if ok {
+		t, err := strconv.Atoi(timeout)
+		if err != nil || t < 1 {
+			return nil, fmt.Errorf("bad \"timeout\" value: %s", timeout)
+		}
+		r.timeout = time.Duration(t) * time.Second
+	}
+
+	if !r.shell {
+		fields := splitArgs(command)
+		if len(fields) < 1 {
+			return nil, fmt.Errorf("empty command")
+		}
+		r.command = unquote(fields[0])
+		for _, f := range fields[1:] {
+			r.args = append(r.args, unquote(f))
+		}
+	}
+	return r, nil
+}
@@ -3,40 +3,233 @@
 package alert
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// file formats
+const (
+	fileFormatText  = "text"
+	fileFormatJSONL = "jsonl"
+	fileKeep        = 5
+	fileTimeFormat  = "2006-01-02 15:04:05"
+	fileLockPoll    = 50 * time.Millisecond
+)
+
+var (
+	// serializes the writes of this process per path
+	fileLocks   = make(map[string]*sync.Mutex)
+	fileLocksMu sync.Mutex
+	// the paths already truncated by this process, the
+	// alerts are recreated on each run of watch
+	fileTruncated = make(map[string]bool)
+)
+
 // File alert file struct
 type File struct {
 	path     string
 	truncate bool
+	format   string
+	maxSize  int64
+	maxAge   time.Duration
+	keep     int
 	options  map[string]string
 }
 
+func fileLock(path string) *sync.Mutex {
+	fileLocksMu.Lock()
+	defer fileLocksMu.Unlock()
+	mut, ok := fileLocks[path]
+	if !ok {
+		mut = &sync.Mutex{}
+		fileLocks[path] = mut
+	}
+	return mut
+}
+
+// truncateOnce truncates a file once per process
+func truncateOnce(path string) error {
+	fileLocksMu.Lock()
+	defer fileLocksMu.Unlock()
+	if fileTruncated[path] {
+		return nil
+	}
+	fileTruncated[path] = true
+	log.Debugf("truncate %s", path)
+	return os.Truncate(path, 0)
+}
+
+func (a *File) line(e *Event) ([]byte, error) {
+	if a.format == fileFormatJSONL {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	}
+	line := fmt.Sprintf("[%s] %s\n", time.Now().Format(fileTimeFormat), flatten(e.String()))
+	return []byte(line), nil
+}
+
+// flatten joins the lines of a multi-line message
+// to keep a single line per event
+func flatten(msg string) string {
+	var fields []string
+	for _, l := range strings.Split(msg, "\n") {
+		l = strings.TrimSpace(l)
+		if len(l) > 0 {
+			fields = append(fields, l)
+		}
+	}
+	return strings.Join(fields, " | ")
+}
+
+// firstTime returns the time of the first entry of the file
+func (a *File) firstTime(f *os.File) (time.Time, error) {
+	_, err := f.Seek(0, 0)
+	if err != nil {
+		return time.Time{}, err
+	}
+	first, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if a.format == fileFormatJSONL {
+		var e Event
+		err = json.Unmarshal([]byte(first), &e)
+		return e.Time, err
+	}
+	end := strings.Index(first, "]")
+	if !strings.HasPrefix(first, "[") || end < 0 {
+		return time.Time{}, fmt.Errorf("no timestamp")
+	}
+	return time.ParseInLocation(fileTimeFormat, first[1:end], time.Local)
+}
+
+func (a *File) needRotate(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil || fi.Size() < 1 {
+		return false
+	}
+	if a.maxSize > 0 && fi.Size() >= a.maxSize {
+		return true
+	}
+	if a.maxAge > 0 {
+		t, err := a.firstTime(f)
+		if err == nil && time.Since(t) >= a.maxAge {
+			return true
+		}
+	}
+	return false
+}
+
+// rotate shifts path to path.1, path.1 to path.2, ...
+// and removes what is above the retention count
+func (a *File) rotate() error {
+	log.Debugf("rotate %s", a.path)
+	_ = os.Remove(fmt.Sprintf("%s.%d", a.path, a.keep))
+	for i := a.keep - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", a.path, i), fmt.Sprintf("%s.%d", a.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if a.keep < 1 {
+		return os.Remove(a.path)
+	}
+	return os.Rename(a.path, a.path+".1")
+}
+
+// lock locks the file, waiting for the other processes until ctx is done
+func lock(ctx context.Context, f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err != syscall.EWOULDBLOCK {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for the lock on %s: %w", f.Name(), ctx.Err())
+		case <-time.After(fileLockPoll):
+		}
+	}
+}
+
+// open opens and locks the file, the file may have been
+// rotated by another process while waiting for the lock
+func (a *File) open(ctx context.Context) (*os.File, error) {
+	for {
+		f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, err
+		}
+		err = lock(ctx, f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		cur, err := os.Stat(a.path)
+		if err == nil && os.SameFile(fi, cur) {
+			return f, nil
+		}
+		f.Close()
+	}
+}
+
 // Notify notifies
-func (a *File) Notify(content string) error {
-	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+func (a *File) Notify(ctx context.Context, e *Event) error {
+	line, err := a.line(e)
+	if err != nil {
+		return err
+	}
+
+	mut := fileLock(a.path)
+	mut.Lock()
+	defer mut.Unlock()
+
+	f, err := a.open(ctx)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	// now
-	t := time.Now()
-	now := t.Format("2006-01-02 15:04:05")
+	if a.needRotate(f) {
+		err = a.rotate()
+		if err != nil {
+			return err
+		}
+		// the lock is released on close
+		f.Close()
+		f, err = a.open(ctx)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+	}
 
-	// append
-	line := fmt.Sprintf("[%s] %s\n", now, content)
-	_, err = f.WriteString(line)
+	// a single write per line
+	_, err = f.Write(line)
 	if err != nil {
 		return err
 	}
-	return nil
+	return f.Sync()
 }
 
 // GetOptions returns this alert options
@@ -49,6 +242,33 @@ func (a *File) GetDescription() string {
 	return fmt.Sprintf("alert to file %s", a.path)
 }
 
+// Close does nothing
+func (a *File) Close() error {
+	return nil
+}
+
+// parseSize parses a size in bytes with an optional K, M or G suffix
+func parseSize(value string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(value))
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(v, "K"):
+		mult = 1024
+	case strings.HasSuffix(v, "M"):
+		mult = 1024 * 1024
+	case strings.HasSuffix(v, "G"):
+		mult = 1024 * 1024 * 1024
+	}
+	if mult > 1 {
+		v = v[:len(v)-1]
+	}
+	size, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("bad size: %s", value)
+	}
+	return size * mult, nil
+}
+
 // NewAlertFile creates a new file alert instance
 func NewAlertFile(options map[string]string) (*File, error) {
 	path, ok := options["path"]
@@ -56,25 +276,63 @@ func NewAlertFile(options map[string]string) (*File, error) {
 		return nil, fmt.Errorf("\"path\" option required")
 	}
 
+	format, ok := options["format"]
+	if !ok {
+		format = fileFormatText
+	}
+	if format != fileFormatText && format != fileFormatJSONL {
+		return nil, fmt.Errorf("bad \"format\" value: %s", format)
+	}
+
+	var maxSize int64
+	var err error
+	size, ok := options["max_size"]
+	if ok {
+		maxSize, err = parseSize(size)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var maxAge time.Duration
+	age, ok := options["max_age"]
+	if ok {
+		maxAge, err = time.ParseDuration(age)
+		if err != nil {
+			return nil, fmt.Errorf("bad \"max_age\" value: %v", err)
+		}
+	}
+
+	keep := fileKeep
+	k, ok := options["keep"]
+	if ok {
+		keep, err = strconv.Atoi(k)
+		if err != nil || keep < 0 {
+			return nil, fmt.Errorf("bad \"keep\" value: %s", k)
+		}
+	}
+
 	truncate := false
 	trunc, ok := options["truncate"]
 	if ok {
 		log.Debugf("truncate value: %s", trunc)
-		truncate = trunc == "1" || strings.ToLower(trunc) == "true"
+		truncate = isTrue(trunc)
 	}
 
 	if truncate {
-		// truncate file
-		err := os.Truncate(path, 0)
+		err := truncateOnce(path)
 		if err != nil {
 			log.Errorf("%v", err)
 		}
-		log.Debugf("truncate %s", path)
 	}
 
 	a := &File{
 		path:     path,
 		truncate: truncate,
+		format:   format,
+		maxSize:  maxSize,
+		maxAge:   maxAge,
+		keep:     keep,
 		options:  options,
 	}
 	return a, nil
@@ -0,0 +1,105 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Gotify alert struct
+type Gotify struct {
+	server   string
+	token    string
+	priority int
+	options  map[string]string
+}
+
+// gotify priorities go from 0 (silent) to 10
+func gotifyPriority(severity string) int {
+	switch severity {
+	case SeverityInfo:
+		return 2
+	case SeverityWarning:
+		return 5
+	}
+	return 8
+}
+
+// Notify notifies
+func (a *Gotify) Notify(ctx context.Context, e *Event) error {
+	priority := a.priority
+	if priority < 0 {
+		priority = gotifyPriority(e.Severity)
+	}
+
+	data := map[string]interface{}{
+		"title":    e.Title(),
+		"message":  e.String(),
+		"priority": priority,
+	}
+	jData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/message", a.server)
+	headers := map[string]string{
+		"Content-Type": "application/json",
+		"X-Gotify-Key": a.token,
+	}
+	return httpSend(ctx, "POST", url, jData, headers)
+}
+
+// GetOptions returns this alert options
+func (a *Gotify) GetOptions() map[string]string {
+	return a.options
+}
+
+// GetDescription returns a description for this alert
+func (a *Gotify) GetDescription() string {
+	return fmt.Sprintf("alert to gotify %s", a.server)
+}
+
+// Close does nothing
+func (a *Gotify) Close() error {
+	return nil
+}
+
+// NewAlertGotify creates a new gotify alert instance
+func NewAlertGotify(options map[string]string) (*Gotify, error) {
+	server, ok := options["server"]
+	if !ok {
+		return nil, fmt.Errorf("\"server\" option required")
+	}
+
+	token, ok := options["token"]
+	if !ok {
+		return nil, fmt.Errorf("\"token\" option required")
+	}
+
+	// negative means derived from the severity
+	priority := -1
+	v, ok := options["priority"]
+	if ok {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i > 10 {
+			return nil, fmt.Errorf("\"priority\" must be between 0 and 10")
+		}
+		priority = i
+	}
+
+	a := &Gotify{
+		server:   strings.TrimSuffix(server, "/"),
+		token:    token,
+		priority: priority,
+		options:  options,
+	}
+	return a, nil
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	httpTimeout = 30 * time.Second
+)
+
+// httpSend sends a request and fails on non 2xx status
+func httpSend(ctx context.Context, method string, address string, body []byte, headers map[string]string) error {
+	client := &http.Client{
+		Timeout: httpTimeout,
+	}
+	return httpSendWith(ctx, client, method, address, body, headers)
+}
+
+// redact removes the url from an error, it may contain a token
+func redact(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// httpSendWith sends a request with a specific client
+func httpSendWith(ctx context.Context, client *http.Client, method string, address string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, method, address, bytes.NewBuffer(body))
+	if err != nil {
+		return redact(err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("User-Agent", "checkah")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return redact(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("return status: %s %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/deadc0de6/checkah/internal/logsink"
+)
+
+// Journald alert struct
+type Journald struct {
+	sink    *logsink.Journal
+	options map[string]string
+}
+
+// Notify notifies
+func (a *Journald) Notify(ctx context.Context, e *Event) error {
+	fields := map[string]string{
+		"CHECKAH_SEVERITY": e.Severity,
+		"CHECKAH_STATUS":   e.Status,
+	}
+	if len(e.Host) > 0 {
+		fields["CHECKAH_HOST"] = e.Host
+	}
+	if len(e.Check) > 0 {
+		fields["CHECKAH_CHECK"] = e.Check
+		fields["CHECKAH_DESCRIPTION"] = e.Description
+	}
+	if e.Failures > 0 {
+		fields["CHECKAH_FAILURES"] = strconv.Itoa(e.Failures)
+	}
+	return a.sink.Send(ctx, syslogSeverity(e), fields, e.String())
+}
+
+// GetOptions returns this alert options
+func (a *Journald) GetOptions() map[string]string {
+	return a.options
+}
+
+// GetDescription returns a description for this alert
+func (a *Journald) GetDescription() string {
+	return "alert to journald"
+}
+
+// Close closes the connection to the journal
+func (a *Journald) Close() error {
+	return a.sink.Close()
+}
+
+// NewAlertJournald creates a new journald alert instance
+func NewAlertJournald(options map[string]string) (*Journald, error) {
+	sink, err := logsink.NewJournal(options["socket"])
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Journald{
+		sink:    sink,
+		options: options,
+	}
+	return a, nil
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	ntfyServer = "https://ntfy.sh"
+)
+
+// Ntfy alert struct
+type Ntfy struct {
+	server   string
+	topic    string
+	priority string
+	tags     string
+	token    string
+	options  map[string]string
+}
+
+// ntfy priorities go from 1 (min) to 5 (max)
+func ntfyPriority(severity string) string {
+	switch severity {
+	case SeverityInfo:
+		return "3"
+	case SeverityWarning:
+		return "4"
+	}
+	return "5"
+}
+
+// Notify notifies
+func (a *Ntfy) Notify(ctx context.Context, e *Event) error {
+	priority := a.priority
+	if len(priority) < 1 {
+		priority = ntfyPriority(e.Severity)
+	}
+
+	headers := map[string]string{
+		"Title":    e.Title(),
+		"Priority": priority,
+	}
+	if len(a.tags) > 0 {
+		headers["Tags"] = a.tags
+	}
+	if len(a.token) > 0 {
+		headers["Authorization"] = fmt.Sprintf("Bearer %s", a.token)
+	}
+
+	url := fmt.Sprintf("%s/%s", a.server, a.topic)
+	return httpSend(ctx, "POST", url, []byte(e.String()), headers)
+}
+
+// GetOptions returns this alert options
+func (a *Ntfy) GetOptions() map[string]string {
+	return a.options
+}
+
+// GetDescription returns a description for this alert
+func (a *Ntfy) GetDescription() string {
+	return fmt.Sprintf("alert to ntfy %s/%s", a.server, a.topic)
+}
+
+// Close does nothing
+func (a *Ntfy) Close() error {
+	return nil
+}
+
+// NewAlertNtfy creates a new ntfy alert instance
+func NewAlertNtfy(options map[string]string) (*Ntfy, error) {
+	topic, ok := options["topic"]
+	if !ok {
+		return nil, fmt.Errorf("\"topic\" option required")
+	}
+
+	server, ok := options["server"]
+	if !ok {
+		server = ntfyServer
+	}
+
+	priority := options["priority"]
+	if len(priority) > 0 {
+		i, err := strconv.Atoi(priority)
+		if err != nil {
+			return nil, err
+		}
+		if i < 1 || i > 5 {
+			return nil, fmt.Errorf("\"priority\" must be between 1 and 5")
+		}
+	}
+
+	a := &Ntfy{
+		server:   strings.TrimSuffix(server, "/"),
+		topic:    topic,
+		priority: priority,
+		tags:     options["tags"],
+		token:    options["token"],
+		options:  options,
+	}
+	return a, nil
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deadc0de6/checkah/internal/state"
+	log "github.com/sirupsen/logrus"
+)
+
+// RateLimits keeps track of the notifications
+// sent by each alert across runs
+type RateLimits struct {
+	path  string
+	sent  map[string][]time.Time
+	mut   *sync.Mutex
+	dirty bool
+}
+
+// allow records a notification if allowed
+func (r *RateLimits) allow(key string, count int, window time.Duration) bool {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	now := time.Now()
+	var recent []time.Time
+	for _, t := range r.sent[key] {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+
+	ok := len(recent) < count
+	if ok {
+		recent = append(recent, now)
+	}
+	r.sent[key] = recent
+	r.dirty = true
+	return ok
+}
+
+// Save writes the rate limits to disk
+func (r *RateLimits) Save() error {
+	if r == nil {
+		return nil
+	}
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if !r.dirty {
+		return nil
+	}
+	return state.Save(r.path, r.sent)
+}
+
+// NewRateLimits loads the rate limits from disk
+func NewRateLimits(path string) (*RateLimits, error) {
+	sent := make(map[string][]time.Time)
+	err := state.Load(path, &sent)
+	if state.IsCorrupt(err) {
+		log.Errorf("ignoring corrupt rate limits %s: %v", path, err)
+		sent = make(map[string][]time.Time)
+	} else if err != nil {
+		return nil, err
+	}
+
+	r := &RateLimits{
+		path: path,
+		sent: sent,
+		mut:  &sync.Mutex{},
+	}
+	return r, nil
+}
+
+// RateLimited wraps an alert to send at most
+// count notifications per window
+type RateLimited struct {
+	alert  Alert
+	count  int
+	window time.Duration
+	limits *RateLimits
+}
+
+// Notify notifies
+func (a *RateLimited) Notify(ctx context.Context, e *Event) error {
+	if !a.limits.allow(Key(a), a.count, a.window) {
+		log.Warnf("%s rate limited (%d per %v), dropping: %s", a.GetDescription(), a.count, a.window, e.String())
+		return nil
+	}
+	return a.alert.Notify(ctx, e)
+}
+
+// GetOptions returns this alert options
+func (a *RateLimited) GetOptions() map[string]string {
+	return a.alert.GetOptions()
+}
+
+// GetDescription returns a description for this alert
+func (a *RateLimited) GetDescription() string {
+	return a.alert.GetDescription()
+}
+
+// Close closes the alert
+func (a *RateLimited) Close() error {
+	return a.alert.Close()
+}
+
+// ParseRateLimit parses a "<max>/<window>" rate limit (for example "10/1h")
+func ParseRateLimit(limit string) (int, time.Duration, error) {
+	fields := strings.Split(limit, "/")
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("bad rate limit \"%s\", expecting <max>/<window>", limit)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad rate limit \"%s\": %v", limit, err)
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(fields[1]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad rate limit \"%s\": %v", limit, err)
+	}
+	if count < 1 || window <= 0 {
+		return 0, 0, fmt.Errorf("bad rate limit \"%s\": must be positive", limit)
+	}
+	return count, window, nil
+}
+
+// NewRateLimited wraps an alert
+func NewRateLimited(a Alert, count int, window time.Duration, limits *RateLimits) *RateLimited {
+	r := &RateLimited{
+		alert:  a,
+		count:  count,
+		window: window,
+		limits: limits,
+	}
+	return r
+}
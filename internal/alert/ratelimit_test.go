@@ -0,0 +1,91 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		limit  string
+		count  int
+		window time.Duration
+		ok     bool
+	}{
+		{"10/1h", 10, time.Hour, true},
+		{" 3 / 30m ", 3, 30 * time.Minute, true},
+		{"1/1s", 1, time.Second, true},
+		{"10", 0, 0, false},
+		{"10/1h/2", 0, 0, false},
+		{"x/1h", 0, 0, false},
+		{"10/x", 0, 0, false},
+		{"0/1h", 0, 0, false},
+		{"-1/1h", 0, 0, false},
+		{"10/0s", 0, 0, false},
+	}
+	for _, tt := range tests {
+		count, window, err := ParseRateLimit(tt.limit)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseRateLimit(%q) error = %v, want ok %t", tt.limit, err, tt.ok)
+			continue
+		}
+		if count != tt.count || window != tt.window {
+			t.Errorf("ParseRateLimit(%q) = %d, %v, want %d, %v", tt.limit, count, window, tt.count, tt.window)
+		}
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	limits, err := NewRateLimits(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := newFake("alert to webhook", map[string]string{"url": "http://a"})
+	b := newFake("alert to webhook", map[string]string{"url": "http://b"})
+	window := 200 * time.Millisecond
+	ra := NewRateLimited(a, 2, window, limits)
+	rb := NewRateLimited(b, 2, window, limits)
+
+	notify := func(r *RateLimited, count int) {
+		for i := 0; i < count; i++ {
+			err := r.Notify(t.Context(), NewEvent("web1", "disk", "disk usage", "full", SeverityCritical))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// at most 2 per window, each alert has its own
+	notify(ra, 3)
+	notify(rb, 1)
+	if a.count() != 2 || b.count() != 1 {
+		t.Errorf("delivered %d and %d, want 2 and 1", a.count(), b.count())
+	}
+
+	// kept across runs
+	err = limits.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits, err = NewRateLimits(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ra = NewRateLimited(a, 2, window, limits)
+	notify(ra, 1)
+	if a.count() != 2 {
+		t.Errorf("delivered %d after reload, want 2", a.count())
+	}
+
+	// allowed again once the window is over
+	time.Sleep(window)
+	notify(ra, 3)
+	if a.count() != 4 {
+		t.Errorf("delivered %d after the window, want 4", a.count())
+	}
+}
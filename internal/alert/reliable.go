@@ -0,0 +1,115 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	backoffBase = 1 * time.Second
+	backoffMax  = 30 * time.Second
+)
+
+// Reliable wraps an alert with a timeout,
+// retries and a fallback alert
+type Reliable struct {
+	alert    Alert
+	timeout  time.Duration
+	retries  int
+	fallback Alert
+	spool    *Spool
+}
+
+// notifyOnce notifies, the delivery is stopped after the timeout
+func (a *Reliable) notifyOnce(ctx context.Context, e *Event) error {
+	if a.timeout <= 0 {
+		return a.alert.Notify(ctx, e)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+	err := a.alert.Notify(tctx, e)
+	if err != nil && ctx.Err() == nil && tctx.Err() != nil {
+		return fmt.Errorf("timeout after %v: %v", a.timeout, err)
+	}
+	return err
+}
+
+// Notify notifies, retrying with backoff and using the fallback
+// if all attempts failed, what is not delivered is spooled
+func (a *Reliable) Notify(ctx context.Context, e *Event) error {
+	var err error
+	backoff := backoffBase
+	for i := 0; i <= a.retries; i++ {
+		if i > 0 {
+			log.Debugf("retrying %s in %v (%d/%d): %v", a.GetDescription(), backoff, i, a.retries, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(backoff):
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			backoff *= 2
+			if backoff > backoffMax {
+				backoff = backoffMax
+			}
+		}
+		err = a.notifyOnce(ctx, e)
+		if err == nil {
+			return nil
+		}
+	}
+
+	if a.fallback != nil && ctx.Err() == nil {
+		log.Debugf("%s failed, using fallback %s", a.GetDescription(), a.fallback.GetDescription())
+		ferr := a.fallback.Notify(ctx, e)
+		if ferr == nil {
+			log.Debugf("%s failed but delivered to fallback: %v", a.GetDescription(), err)
+			return nil
+		}
+		err = fmt.Errorf("%v (fallback: %v)", err, ferr)
+	}
+
+	a.spool.Add(a, e)
+	return err
+}
+
+// GetOptions returns this alert options
+func (a *Reliable) GetOptions() map[string]string {
+	return a.alert.GetOptions()
+}
+
+// GetDescription returns a description for this alert
+func (a *Reliable) GetDescription() string {
+	return a.alert.GetDescription()
+}
+
+// Close closes the alert and its fallback
+func (a *Reliable) Close() error {
+	err := a.alert.Close()
+	if a.fallback != nil {
+		ferr := a.fallback.Close()
+		if err == nil {
+			err = ferr
+		}
+	}
+	return err
+}
+
+// NewReliable wraps an alert
+func NewReliable(a Alert, timeout time.Duration, retries int, fallback Alert, spool *Spool) *Reliable {
+	r := &Reliable{
+		alert:    a,
+		timeout:  timeout,
+		retries:  retries,
+		fallback: fallback,
+		spool:    spool,
+	}
+	return r
+}
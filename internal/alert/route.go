@@ -0,0 +1,107 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Route selects the events an alert receives
+type Route struct {
+	// severities and/or statuses, failures of any
+	// severity when empty
+	On []string
+	// check names or descriptions (globs)
+	Checks []string
+	// host tags (globs)
+	Tags []string
+}
+
+func matchAny(patterns []string, values ...string) bool {
+	for _, p := range patterns {
+		for _, v := range values {
+			ok, _ := path.Match(p, v)
+			if ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Accepts returns true if the event is routed to the alert,
+// events not about a check or a host are only filtered with "notify-on",
+// recoveries must be explicitly asked for
+func (r *Route) Accepts(e *Event) bool {
+	if e.Status == StatusRecovery {
+		if !contains(r.On, StatusRecovery) {
+			return false
+		}
+	} else if len(r.On) > 0 && !contains(r.On, StatusProblem) && !contains(r.On, e.Severity) {
+		return false
+	}
+
+	if len(r.Checks) > 0 && len(e.Check) > 0 && !matchAny(r.Checks, e.Check, e.Description) {
+		return false
+	}
+	if len(r.Tags) > 0 && len(e.Host) > 0 && !matchAny(r.Tags, e.Tags...) {
+		return false
+	}
+	return true
+}
+
+// NewRoute creates and validates a route
+func NewRoute(on []string, checks []string, tags []string) (*Route, error) {
+	r := &Route{}
+	for _, o := range on {
+		v := strings.ToLower(o)
+		switch v {
+		case SeverityInfo, SeverityWarning, SeverityCritical, StatusProblem, StatusRecovery:
+		default:
+			return nil, fmt.Errorf("bad \"notify-on\" value: %s", o)
+		}
+		r.On = append(r.On, v)
+	}
+
+	patterns := append(append([]string{}, checks...), tags...)
+	for _, p := range patterns {
+		_, err := path.Match(p, "")
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern \"%s\": %v", p, err)
+		}
+	}
+	r.Checks = checks
+	r.Tags = tags
+	return r, nil
+}
+
+// Routed an alert only receiving the events of its route
+type Routed struct {
+	Alert
+	route *Route
+}
+
+// Accepts returns true if the event is routed to this alert
+func (a *Routed) Accepts(e *Event) bool {
+	return a.route.Accepts(e)
+}
+
+// NewRouted wraps an alert with a route
+func NewRouted(a Alert, route *Route) *Routed {
+	r := &Routed{
+		Alert: a,
+		route: route,
+	}
+	return r
+}
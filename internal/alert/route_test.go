@@ -0,0 +1,81 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"testing"
+)
+
+func TestNewRoute(t *testing.T) {
+	tests := []struct {
+		on     []string
+		checks []string
+		tags   []string
+		ok     bool
+	}{
+		{nil, nil, nil, true},
+		{[]string{"critical", "WARNING", "info", "problem", "recovery"}, nil, nil, true},
+		{[]string{"fatal"}, nil, nil, false},
+		{nil, []string{"disk*"}, []string{"prod"}, true},
+		{nil, []string{"disk["}, nil, false},
+		{nil, nil, []string{"pr[od"}, false},
+	}
+	for _, tt := range tests {
+		_, err := NewRoute(tt.on, tt.checks, tt.tags)
+		if (err == nil) != tt.ok {
+			t.Errorf("NewRoute(%v, %v, %v) error = %v, want ok %t", tt.on, tt.checks, tt.tags, err, tt.ok)
+		}
+	}
+}
+
+func TestRouteAccepts(t *testing.T) {
+	event := func(host string, check string, severity string, status string, tags ...string) *Event {
+		e := NewEvent(host, check, check+" usage", "msg", severity)
+		e.Status = status
+		e.Tags = tags
+		return e
+	}
+	critical := event("web1", "disk", SeverityCritical, StatusProblem, "prod")
+	warning := event("web1", "load", SeverityWarning, StatusProblem, "dev")
+	recovery := event("web1", "disk", SeverityCritical, StatusRecovery, "prod")
+	summary := event("", "", SeverityCritical, StatusProblem)
+
+	tests := []struct {
+		name   string
+		on     []string
+		checks []string
+		tags   []string
+		event  *Event
+		want   bool
+	}{
+		{"default problem", nil, nil, nil, critical, true},
+		{"default warning", nil, nil, nil, warning, true},
+		{"default no recovery", nil, nil, nil, recovery, false},
+		{"severity", []string{"critical"}, nil, nil, critical, true},
+		{"other severity", []string{"critical"}, nil, nil, warning, false},
+		{"problem", []string{"problem"}, nil, nil, warning, true},
+		{"recovery", []string{"recovery"}, nil, nil, recovery, true},
+		{"recovery only", []string{"recovery"}, nil, nil, critical, false},
+		{"check name", nil, []string{"disk"}, nil, critical, true},
+		{"check description", nil, []string{"disk usage"}, nil, critical, true},
+		{"check glob", nil, []string{"lo*"}, nil, warning, true},
+		{"other check", nil, []string{"disk"}, nil, warning, false},
+		{"tag", nil, nil, []string{"prod"}, critical, true},
+		{"tag glob", nil, nil, []string{"d*"}, warning, true},
+		{"other tag", nil, nil, []string{"prod"}, warning, false},
+		{"recovery of a routed check", []string{"recovery"}, []string{"disk"}, []string{"prod"}, recovery, true},
+		// events not about a check or a host
+		{"summary with checks", nil, []string{"disk"}, []string{"prod"}, summary, true},
+		{"summary severity", []string{"warning"}, nil, nil, summary, false},
+	}
+	for _, tt := range tests {
+		r, err := NewRoute(tt.on, tt.checks, tt.tags)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := r.Accepts(tt.event)
+		if got != tt.want {
+			t.Errorf("%s: Accepts = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
@@ -3,24 +3,20 @@
 package alert
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 )
 
 // Script alert file struct
 type Script struct {
-	command string
-	args    []string
+	runner  *runner
 	options map[string]string
 }
 
 // Notify notifies
-func (a *Script) Notify(content string) error {
-	args := append(a.args, fmt.Sprintf("'%s'", content))
-	cmd := exec.Command(a.command, args...)
-	err := cmd.Run()
-	return err
+func (a *Script) Notify(ctx context.Context, e *Event) error {
+	return a.runner.run(ctx, e)
 }
 
 func fileExists(path string) bool {
@@ -35,7 +31,12 @@ func (a *Script) GetOptions() map[string]string {
 
 // GetDescription returns a description for this alert
 func (a *Script) GetDescription() string {
-	return fmt.Sprintf("alert to script %s", a.command)
+	return fmt.Sprintf("alert to script %s", a.runner.command)
+}
+
+// Close does nothing
+func (a *Script) Close() error {
+	return nil
 }
 
 // NewAlertScript creates a new script alert instance
@@ -45,23 +46,21 @@ func NewAlertScript(options map[string]string) (*Script, error) {
 		return nil, fmt.Errorf("\"path\" option required")
 	}
 
-	if len(command) < 1 {
+	fields := splitArgs(command)
+	if len(fields) < 1 {
 		return nil, fmt.Errorf("\"path\" option required")
 	}
-
-	fields := splitArgs(command)
-	var args []string
-	if len(fields) > 1 {
-		args = fields[1:]
+	if !fileExists(unquote(fields[0])) {
+		return nil, fmt.Errorf("script \"%s\" does not exist", fields[0])
 	}
 
-	if !fileExists(fields[0]) {
-		return nil, fmt.Errorf("script \"%s\" does not exist", fields[0])
+	r, err := newRunner(command, options)
+	if err != nil {
+		return nil, err
 	}
 
 	a := &Script{
-		command: fields[0],
-		args:    args,
+		runner:  r,
 		options: options,
 	}
 	return a, nil
@@ -0,0 +1,118 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"sync"
+	"time"
+
+	"github.com/deadc0de6/checkah/internal/state"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	spoolMaxAge = 24 * time.Hour
+)
+
+// an undelivered notification
+type spoolEntry struct {
+	Alert string `json:"alert"`
+	Key   string `json:"key,omitempty"`
+	Event *Event `json:"event"`
+}
+
+// Spool keeps failed deliveries to retry them on the next run
+type Spool struct {
+	path    string
+	pending []*spoolEntry
+	entries []*spoolEntry
+	mut     *sync.Mutex
+}
+
+// Add spools a failed delivery
+func (s *Spool) Add(a Alert, e *Event) {
+	if s == nil {
+		return
+	}
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	log.Debugf("spooling notification for %s", a.GetDescription())
+	entry := &spoolEntry{
+		Alert: a.GetDescription(),
+		Key:   Key(a),
+		Event: e,
+	}
+	s.entries = append(s.entries, entry)
+}
+
+// Replay retries the deliveries spooled during the previous run
+func (s *Spool) Replay(alerts []Alert, d *Dispatcher) {
+	if s == nil {
+		return
+	}
+	s.mut.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.mut.Unlock()
+
+	for _, entry := range pending {
+		if time.Since(entry.Event.Time) > spoolMaxAge {
+			log.Warnf("dropping spooled notification for \"%s\": too old", entry.Alert)
+			continue
+		}
+
+		var found Alert
+		for _, a := range alerts {
+			// entries spooled by older versions have no key
+			if Key(a) == entry.Key || (len(entry.Key) < 1 && a.GetDescription() == entry.Alert) {
+				found = a
+				break
+			}
+		}
+		if found == nil {
+			log.Warnf("dropping spooled notification for \"%s\": alert not found", entry.Alert)
+			continue
+		}
+
+		log.Debugf("replaying spooled notification for %s", entry.Alert)
+		d.Dispatch(found, entry.Event)
+	}
+}
+
+// Save writes the spool to disk
+func (s *Spool) Save() error {
+	if s == nil {
+		return nil
+	}
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	// keep what was never replayed
+	var entries []*spoolEntry
+	entries = append(entries, s.pending...)
+	entries = append(entries, s.entries...)
+	if entries == nil {
+		entries = []*spoolEntry{}
+	}
+	return state.Save(s.path, entries)
+}
+
+// NewSpool loads a spool from disk
+func NewSpool(path string) (*Spool, error) {
+	var pending []*spoolEntry
+	err := state.Load(path, &pending)
+	if state.IsCorrupt(err) {
+		log.Errorf("ignoring corrupt spool %s: %v", path, err)
+		pending = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	s := &Spool{
+		path:    path,
+		pending: pending,
+		mut:     &sync.Mutex{},
+	}
+	return s, nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSpoolReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool.json")
+	webhookA := newFake("alert to webhook", map[string]string{"url": "http://a"})
+	webhookB := newFake("alert to webhook", map[string]string{"url": "http://b"})
+	gone := newFake("alert to ntfy", map[string]string{"topic": "x"})
+
+	// a run spools the failed deliveries
+	spool, err := NewSpool(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spool.Add(webhookA, NewEvent("web1", "disk", "disk usage", "full", SeverityCritical))
+	spool.Add(webhookB, NewEvent("web2", "disk", "disk usage", "full", SeverityCritical))
+	old := NewEvent("web3", "disk", "disk usage", "full", SeverityCritical)
+	old.Time = time.Now().Add(-2 * spoolMaxAge)
+	spool.Add(webhookA, old)
+	spool.Add(gone, NewEvent("web4", "disk", "disk usage", "full", SeverityCritical))
+	err = spool.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the next one replays them to the alerts with the same key
+	spool, err = NewSpool(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newFake("alert to webhook", map[string]string{"url": "http://a"})
+	b := newFake("alert to webhook", map[string]string{"url": "http://b"})
+	d := NewDispatcher(1, 10, spool)
+	spool.Replay([]Alert{a, b}, d)
+	err = d.Close(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.count() != 1 || a.events[0].Host != "web1" {
+		t.Errorf("replayed to a: %d, want the web1 event", a.count())
+	}
+	if b.count() != 1 || b.events[0].Host != "web2" {
+		t.Errorf("replayed to b: %d, want the web2 event", b.count())
+	}
+
+	// replayed entries are not spooled again
+	err = spool.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spool, err = NewSpool(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spool.pending) != 0 {
+		t.Errorf("%d entries left, want none", len(spool.pending))
+	}
+}
+
+func TestSpoolLegacyEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool.json")
+	ev := NewEvent("web1", "disk", "disk usage", "full", SeverityCritical)
+	content := `[{"alert": "alert to webhook", "event": {"host": "web1", "time": "` + ev.Time.Format(time.RFC3339Nano) + `"}}]`
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spool, err := NewSpool(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newFake("alert to webhook", map[string]string{"url": "http://a"})
+	d := NewDispatcher(1, 10, spool)
+	spool.Replay([]Alert{a}, d)
+	err = d.Close(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.count() != 1 {
+		t.Errorf("replayed %d, want 1", a.count())
+	}
+}
+
+func TestSpoolCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool.json")
+	err := os.WriteFile(path, []byte(`[{"alert":`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spool, err := NewSpool(path)
+	if err != nil {
+		t.Fatalf("NewSpool of a corrupt file: %v", err)
+	}
+	if len(spool.pending) != 0 {
+		t.Errorf("%d entries, want none", len(spool.pending))
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/deadc0de6/checkah/internal/logsink"
+)
+
+// Syslog alert struct
+type Syslog struct {
+	sink    *logsink.Syslog
+	options map[string]string
+}
+
+// syslogSeverity returns the syslog severity of an event
+func syslogSeverity(e *Event) int {
+	if e.Status == StatusRecovery {
+		return logsink.SeverityInfo
+	}
+	switch e.Severity {
+	case SeverityInfo:
+		return logsink.SeverityInfo
+	case SeverityWarning:
+		return logsink.SeverityWarning
+	}
+	return logsink.SeverityCritical
+}
+
+// Notify notifies
+func (a *Syslog) Notify(ctx context.Context, e *Event) error {
+	data := map[string]string{
+		"severity": e.Severity,
+		"status":   e.Status,
+	}
+	if len(e.Host) > 0 {
+		data["host"] = e.Host
+	}
+	if len(e.Check) > 0 {
+		data["check"] = e.Check
+		data["description"] = e.Description
+	}
+	if e.Failures > 0 {
+		data["failures"] = strconv.Itoa(e.Failures)
+	}
+	return a.sink.Send(ctx, syslogSeverity(e), e.Status, data, e.String())
+}
+
+// GetOptions returns this alert options
+func (a *Syslog) GetOptions() map[string]string {
+	return a.options
+}
+
+// GetDescription returns a description for this alert
+func (a *Syslog) GetDescription() string {
+	address, ok := a.options["address"]
+	if !ok {
+		address = "local socket"
+	}
+	return fmt.Sprintf("alert to syslog %s", address)
+}
+
+// Close closes the connection to the syslog
+func (a *Syslog) Close() error {
+	return a.sink.Close()
+}
+
+// NewAlertSyslog creates a new syslog alert instance
+func NewAlertSyslog(options map[string]string) (*Syslog, error) {
+	sink, err := logsink.NewSyslog(options["address"], options["facility"], options["tag"])
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Syslog{
+		sink:    sink,
+		options: options,
+	}
+	return a, nil
+}
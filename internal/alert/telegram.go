@@ -0,0 +1,83 @@
+// Copyright (c) 2021 deadc0de6
+
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	telegramServer = "https://api.telegram.org"
+)
+
+// Telegram alert struct
+type Telegram struct {
+	server  string
+	token   string
+	chatID  string
+	options map[string]string
+}
+
+// Notify notifies
+func (a *Telegram) Notify(ctx context.Context, e *Event) error {
+	data := map[string]interface{}{
+		"chat_id": a.chatID,
+		"text":    fmt.Sprintf("[%s] %s", strings.ToUpper(e.Severity), e.String()),
+		// only critical and warning make the phone ring
+		"disable_notification": e.Severity == SeverityInfo,
+	}
+	jData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/bot%s/sendMessage", a.server, a.token)
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+	return httpSend(ctx, "POST", url, jData, headers)
+}
+
+// GetOptions returns this alert options
+func (a *Telegram) GetOptions() map[string]string {
+	return a.options
+}
+
+// GetDescription returns a description for this alert
+func (a *Telegram) GetDescription() string {
+	return fmt.Sprintf("alert to telegram chat %s", a.chatID)
+}
+
+// Close does nothing
+func (a *Telegram) Close() error {
+	return nil
+}
+
+// NewAlertTelegram creates a new telegram alert instance
+func NewAlertTelegram(options map[string]string) (*Telegram, error) {
+	token, ok := options["token"]
+	if !ok {
+		return nil, fmt.Errorf("\"token\" option required")
+	}
+
+	chatID, ok := options["chat_id"]
+	if !ok {
+		return nil, fmt.Errorf("\"chat_id\" option required")
+	}
+
+	server, ok := options["server"]
+	if !ok {
+		server = telegramServer
+	}
+
+	a := &Telegram{
+		server:  strings.TrimSuffix(server, "/"),
+		token:   token,
+		chatID:  chatID,
+		options: options,
+	}
+	return a, nil
+}
@@ -4,48 +4,64 @@ package alert
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+const (
+	webhookBody        = `{"alert": {{json .String}}}`
+	webhookContentType = "application/json"
+	webhookSignature   = "X-Checkah-Signature"
 )
 
 // Webhook alert file struct
 type Webhook struct {
 	url     string
+	method  string
+	body    *template.Template
+	secret  string
 	headers map[string]string
+	client  *http.Client
 	options map[string]string
 }
 
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// sign returns the HMAC-SHA256 signature of the body
+func (a *Webhook) sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(a.secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
 // Notify notifies
-func (a *Webhook) Notify(content string) error {
-	data := map[string]string{"alert": content}
-	jData, err := json.Marshal(data)
+func (a *Webhook) Notify(ctx context.Context, e *Event) error {
+	var body bytes.Buffer
+	err := a.body.Execute(&body, e)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", a.url, bytes.NewBuffer(jData))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
+	headers := make(map[string]string)
 	for k, v := range a.headers {
-		req.Header.Set(k, v)
+		headers[k] = v
 	}
-	req.Header.Set("User-Agent", "checkah")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	if len(a.secret) > 0 {
+		headers[webhookSignature] = a.sign(body.Bytes())
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("return status: %s", resp.Status)
-	}
-
-	return nil
+	return httpSendWith(ctx, a.client, a.method, a.url, body.Bytes(), headers)
 }
 
 // GetOptions returns this alert options
@@ -58,16 +74,67 @@ func (a *Webhook) GetDescription() string {
 	return fmt.Sprintf("alert to webhook %s", a.url)
 }
 
+// Close does nothing
+func (a *Webhook) Close() error {
+	return nil
+}
+
+func webhookClient(options map[string]string) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig("", options)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	proxy, ok := options["proxy"]
+	if ok {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("bad \"proxy\" value: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+
+	client := &http.Client{
+		Timeout:   httpTimeout,
+		Transport: transport,
+	}
+	return client, nil
+}
+
 // NewAlertWebhook creates a new file alert instance
 func NewAlertWebhook(options map[string]string) (*Webhook, error) {
-	url, ok := options["url"]
+	u, ok := options["url"]
 	if !ok {
 		return nil, fmt.Errorf("\"url\" option required")
 	}
 
+	method, ok := options["method"]
+	if !ok {
+		method = http.MethodPost
+	}
+	method = strings.ToUpper(method)
+
+	body, ok := options["body"]
+	if !ok {
+		body = webhookBody
+	}
+	tmpl, err := template.New("body").Funcs(template.FuncMap{"json": toJSON}).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("bad \"body\" template: %v", err)
+	}
+
+	contentType, ok := options["content_type"]
+	if !ok {
+		contentType = webhookContentType
+	}
+
 	// get headers
-	headers := make(map[string]string)
-	for i := 0; i < 10; i++ {
+	headers := map[string]string{
+		"Content-Type": contentType,
+	}
+	for i := 0; ; i++ {
 		headerName := fmt.Sprintf("header%d", i)
 		valueName := fmt.Sprintf("value%d", i)
 		h, ok := options[headerName]
@@ -81,9 +148,32 @@ func NewAlertWebhook(options map[string]string) (*Webhook, error) {
 		headers[h] = v
 	}
 
+	// authentication
+	user, hasUser := options["user"]
+	token, hasToken := options["token"]
+	if hasUser && hasToken {
+		return nil, fmt.Errorf("\"user\" and \"token\" options are exclusive")
+	}
+	if hasUser {
+		creds := fmt.Sprintf("%s:%s", user, options["password"])
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(creds))
+	}
+	if hasToken {
+		headers["Authorization"] = "Bearer " + token
+	}
+
+	client, err := webhookClient(options)
+	if err != nil {
+		return nil, err
+	}
+
 	a := &Webhook{
-		url:     url,
+		url:     u,
+		method:  method,
+		body:    tmpl,
+		secret:  options["secret"],
 		headers: headers,
+		client:  client,
 		options: options,
 	}
 	return a, nil
@@ -3,6 +3,7 @@
 package check
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/deadc0de6/checkah/internal/transport"
@@ -15,23 +16,25 @@ type Result struct {
 	Value       string
 	Limit       string
 	Error       error
+	// the check did not complete in time
+	Timeout bool
 }
 
 // Check the check interface
 type Check interface {
 	GetName() string
 	GetDescription() string
-	Run(transport.Transport) *Result
+	Run(context.Context, transport.Transport) *Result
 	GetOptions() map[string]string
 }
 
-func cmdExist(cmd string, trans transport.Transport) bool {
-	_, _, err := trans.Execute(fmt.Sprintf("hash %s", cmd))
+func cmdExist(ctx context.Context, cmd string, trans transport.Transport) bool {
+	_, err := trans.Execute(ctx, fmt.Sprintf("hash %s", cmd))
 	return err == nil
 }
 
-func hasProc(trans transport.Transport) bool {
-	_, _, err := trans.Execute("test -d /proc")
+func hasProc(ctx context.Context, trans transport.Transport) bool {
+	_, err := trans.Execute(ctx, "test -d /proc")
 	return err == nil
 }
 
@@ -3,6 +3,7 @@
 package check
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/deadc0de6/checkah/internal/transport"
@@ -26,13 +27,15 @@ func (c *Command) returnCheck(value string, err error) *Result {
 }
 
 // Run executes the check
-func (c *Command) Run(t transport.Transport) *Result {
-	_, _, err := t.Execute(c.command)
-	ret := "fail"
-	if err == nil {
-		ret = "success"
+func (c *Command) Run(ctx context.Context, t transport.Transport) *Result {
+	res, err := t.Execute(ctx, c.command)
+	if res.TransportError {
+		return c.returnCheck("", fmt.Errorf("command did not complete: %v", err))
 	}
-	return c.returnCheck(ret, err)
+	if err != nil {
+		return c.returnCheck(fmt.Sprintf("exit code %d", res.ExitCode), err)
+	}
+	return c.returnCheck("success", nil)
 }
 
 // GetName returns the check name
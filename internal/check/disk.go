@@ -3,6 +3,7 @@
 package check
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -31,11 +32,12 @@ func (c *Disk) getValue(percent string) (int, error) {
 }
 
 // Run executes the check
-func (c *Disk) Run(t transport.Transport) *Result {
-	stdout, _, err := t.Execute(c.command)
+func (c *Disk) Run(ctx context.Context, t transport.Transport) *Result {
+	res, err := t.Execute(ctx, c.command)
 	if err != nil {
 		return c.returnCheck("", err)
 	}
+	stdout := res.Stdout
 
 	lines := strings.Split(stdout, "\n")
 	for _, line := range lines {
@@ -3,6 +3,7 @@
 package check
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -32,11 +33,12 @@ func (c *Loadavg) returnCheck(value string, err error) *Result {
 }
 
 // Run executes the check
-func (c *Loadavg) Run(t transport.Transport) *Result {
-	stdout, _, err := t.Execute(c.command)
+func (c *Loadavg) Run(ctx context.Context, t transport.Transport) *Result {
+	res, err := t.Execute(ctx, c.command)
 	if err != nil {
 		return c.returnCheck("", err)
 	}
+	stdout := res.Stdout
 
 	stdout = strings.TrimSpace(stdout)
 	r, _ := regexp.Compile("load average[s]*: ")
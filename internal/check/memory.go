@@ -3,6 +3,7 @@
 package check
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -95,18 +96,19 @@ func memoryFromFree(stdout string) (int, error) {
 }
 
 // Run executes the check
-func (c *Memory) Run(t transport.Transport) *Result {
+func (c *Memory) Run(ctx context.Context, t transport.Transport) *Result {
 	cmd := "free -t"
 	checker := memoryFromFree
-	if !cmdExist("free", t) {
+	if !cmdExist(ctx, "free", t) {
 		cmd = "memory_pressure"
 		checker = memoryFromMemoryPressure
 	}
 
-	stdout, _, err := t.Execute(cmd)
+	res, err := t.Execute(ctx, cmd)
 	if err != nil {
 		return c.returnCheck("", err)
 	}
+	stdout := res.Stdout
 
 	val, err := checker(stdout)
 	if err != nil {
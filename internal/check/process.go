@@ -3,6 +3,7 @@
 package check
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/deadc0de6/checkah/internal/transport"
@@ -31,14 +32,13 @@ func (c *Process) returnCheck(value string, err error) *Result {
 }
 
 // Run executes the check
-func (c *Process) Run(t transport.Transport) *Result {
-	var isRunning bool
-	_, _, err := t.Execute(c.command)
-	if err != nil {
-		isRunning = false
-	} else {
-		isRunning = true
+func (c *Process) Run(ctx context.Context, t transport.Transport) *Result {
+	res, err := t.Execute(ctx, c.command)
+	if res.TransportError {
+		// neither running nor not running
+		return c.returnCheck("", fmt.Errorf("cannot check process: %v", err))
 	}
+	isRunning := err == nil
 
 	if isRunning {
 		if c.invert {
@@ -3,6 +3,7 @@
 package check
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/deadc0de6/checkah/internal/transport"
@@ -25,8 +26,8 @@ func (c *Reachable) returnCheck(value string, err error) *Result {
 }
 
 // Run executes the check
-func (c *Reachable) Run(t transport.Transport) *Result {
-	_, _, err := t.Execute(c.command)
+func (c *Reachable) Run(ctx context.Context, t transport.Transport) *Result {
+	_, err := t.Execute(ctx, c.command)
 	if err != nil {
 		return c.returnCheck("", fmt.Errorf("host is NOT reachable: %v", err))
 	}
@@ -3,10 +3,12 @@
 package check
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/caarlos0/log"
 	"github.com/deadc0de6/checkah/internal/transport"
@@ -22,6 +24,8 @@ type Script struct {
 const (
 	rmScript     = "rm -f %s"
 	pathOnRemote = "/tmp/checkah.check"
+	// the script is removed even if the check timed out
+	cleanupTimeout = 10 * time.Second
 )
 
 func (c *Script) returnCheck(value string, err error) *Result {
@@ -36,36 +40,38 @@ func (c *Script) returnCheck(value string, err error) *Result {
 }
 
 // Run executes the check
-func (c *Script) Run(t transport.Transport) *Result {
+func (c *Script) Run(ctx context.Context, t transport.Transport) *Result {
 	remotePath := pathOnRemote
 	remoteDir := path.Dir(remotePath)
 
-	err := t.Mkdir(remoteDir)
+	err := t.Mkdir(ctx, remoteDir)
 	if err != nil {
-		return c.returnCheck("", fmt.Errorf("scp create \"%s\" failed: %v", remoteDir, err))
+		return c.returnCheck("", fmt.Errorf("create \"%s\" failed: %v", remoteDir, err))
 	}
 
 	// copy the file over
-	err = t.Copy(c.path, remotePath, "755")
+	err = t.Copy(ctx, c.path, remotePath, "755")
 	if err != nil {
-		return c.returnCheck("", fmt.Errorf("scp \"%s\" to \"%s\" failed: %v", c.path, remotePath, err))
+		return c.returnCheck("", fmt.Errorf("copy \"%s\" to \"%s\" failed: %v", c.path, remotePath, err))
 	}
 	cmd := fmt.Sprintf(rmScript, remotePath)
 	defer func() {
-		_, _, err := t.Execute(cmd)
+		cctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+		defer cancel()
+		_, err := t.Execute(cctx, cmd)
 		if err != nil {
 			log.Errorf("%v", err)
 		}
 	}()
 
 	// execute script
-	sout, serr, err := t.Execute(remotePath)
+	res, err := t.Execute(ctx, remotePath)
 	if err != nil {
 		// return stderr and error
-		return c.returnCheck(serr, fmt.Errorf("remote script \"%s\" failed: %v", remotePath, err))
+		return c.returnCheck(res.Stderr, fmt.Errorf("remote script \"%s\" failed: %v", remotePath, err))
 	}
 
-	sout = strings.TrimSuffix(sout, "\n")
+	sout := strings.TrimSuffix(res.Stdout, "\n")
 	return c.returnCheck(fmt.Sprintf("custom script \"%s\" was successful: %s", c.path, sout), nil)
 }
 
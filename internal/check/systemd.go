@@ -3,6 +3,7 @@
 package check
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -51,14 +52,17 @@ func (c *Systemd) returnCheck(value string, err error) *Result {
 }
 
 // Run executes the check
-func (c *Systemd) Run(t transport.Transport) *Result {
+func (c *Systemd) Run(ctx context.Context, t transport.Transport) *Result {
 	// check service enabled
-	enabled, _, err := t.Execute(c.enabledCommand)
+	res, err := t.Execute(ctx, c.enabledCommand)
+	if res.TransportError {
+		return c.returnCheck("", fmt.Errorf("cannot check service \"%s\": %v", c.serviceName, err))
+	}
 	if err != nil {
 		err2 := fmt.Errorf("no such service \"%s\": %v", c.serviceName, err)
 		return c.returnCheck("", err2)
 	}
-	enabled = strings.TrimSpace(enabled)
+	enabled := strings.TrimSpace(res.Stdout)
 	if c.invert {
 		if enabled == c.serviceEnabled {
 			err := fmt.Errorf("service \"%s\" state is \"%s\"", c.serviceName, enabled)
@@ -72,12 +76,15 @@ func (c *Systemd) Run(t transport.Transport) *Result {
 	}
 
 	// check service running
-	running, _, err := t.Execute(c.runningCommand)
+	res, err = t.Execute(ctx, c.runningCommand)
+	if res.TransportError {
+		return c.returnCheck("", fmt.Errorf("cannot check service \"%s\": %v", c.serviceName, err))
+	}
 	if err != nil {
 		err2 := fmt.Errorf("no such service \"%s\": %v", c.serviceName, err)
 		return c.returnCheck("", err2)
 	}
-	running = strings.TrimSpace(running)
+	running := strings.TrimSpace(res.Stdout)
 	if c.invert {
 		if running == c.serviceRunning {
 			err := fmt.Errorf("service \"%s\" running state is \"%s\"", c.serviceName, running)
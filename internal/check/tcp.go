@@ -3,6 +3,7 @@
 package check
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/deadc0de6/checkah/internal/transport"
@@ -27,8 +28,11 @@ func (c *Port) returnCheck(value string, err error) *Result {
 }
 
 // Run executes the check
-func (c *Port) Run(t transport.Transport) *Result {
-	_, _, err := t.Execute(c.command)
+func (c *Port) Run(ctx context.Context, t transport.Transport) *Result {
+	res, err := t.Execute(ctx, c.command)
+	if res.TransportError {
+		return c.returnCheck("", fmt.Errorf("cannot check port: %v", err))
+	}
 	if err != nil {
 		return c.returnCheck("", fmt.Errorf("closed/filtered"))
 	}
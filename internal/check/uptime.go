@@ -3,6 +3,7 @@
 package check
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -72,18 +73,19 @@ func uptimeFromLoadavg(stdout string) (int, error) {
 }
 
 // Run executes the check
-func (c *Uptime) Run(t transport.Transport) *Result {
+func (c *Uptime) Run(ctx context.Context, t transport.Transport) *Result {
 	cmd := "cat /proc/uptime"
 	checker := uptimeFromProc
-	if !hasProc(t) {
+	if !hasProc(ctx, t) {
 		cmd = "uptime"
 		checker = uptimeFromLoadavg
 	}
 
-	stdout, _, err := t.Execute(cmd)
+	res, err := t.Execute(ctx, cmd)
 	if err != nil {
 		return c.returnCheck("", err)
 	}
+	stdout := res.Stdout
 
 	stdout = strings.TrimSpace(stdout)
 	val, err := checker(stdout)
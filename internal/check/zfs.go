@@ -3,6 +3,7 @@
 package check
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -30,11 +31,12 @@ func (c *Zfs) getInt(str string) (int, error) {
 }
 
 // Run executes the check
-func (c *Zfs) Run(t transport.Transport) *Result {
-	stdout, _, err := t.Execute(c.command)
+func (c *Zfs) Run(ctx context.Context, t transport.Transport) *Result {
+	res, err := t.Execute(ctx, c.command)
 	if err != nil {
 		return c.returnCheck("", err)
 	}
+	stdout := res.Stdout
 
 	// NAME     USED  AVAIL  MOUNTPOINT
 	lines := strings.Split(stdout, "\n")
@@ -58,6 +58,11 @@ func getEmptyConfig() Config {
 		Settings: Settings{
 			HostsParallel:  false,
 			ChecksParallel: true,
+			AlertWorkers:   4,
+			AlertQueue:     100,
+			AlertFlush:     "60",
+			SSHKeepalive:   "30",
+			SSHMaxIdle:     16,
 		},
 		Hosts:    []Host{},
 		Profiles: []Profile{},
@@ -116,6 +121,10 @@ func MergeConfigs(left *Config, right *Config) (*Config, error) {
 	n.Profiles = append(n.Profiles, left.Profiles...)
 	n.Profiles = append(n.Profiles, right.Profiles...)
 
+	// merge maintenance windows
+	n.Maintenance = append(n.Maintenance, left.Maintenance...)
+	n.Maintenance = append(n.Maintenance, right.Maintenance...)
+
 	return n, nil
 }
 
@@ -4,16 +4,27 @@ package config
 
 // Config config file content
 type Config struct {
-	Settings Settings  `mapstruture:"settings" json:"settings"`
-	Hosts    []Host    `mapstructure:"hosts" json:"hosts"`
-	Profiles []Profile `mapstructure:"profiles" json:"profiles"`
+	Settings    Settings      `mapstruture:"settings" json:"settings"`
+	Hosts       []Host        `mapstructure:"hosts" json:"hosts"`
+	Profiles    []Profile     `mapstructure:"profiles" json:"profiles"`
+	Maintenance []Maintenance `mapstructure:"maintenance" json:"maintenance,omitempty"`
 }
 
 // Settings the settings
 type Settings struct {
-	HostsParallel  bool  `mapstructure:"hosts-parallel" json:"hosts-parallel"`
-	ChecksParallel bool  `mapstructure:"checks-parallel" json:"checks-parallel"`
-	GlobalAlert    Alert `mapstructure:"global-alert" json:"global-alert"`
+	HostsParallel  bool              `mapstructure:"hosts-parallel" json:"hosts-parallel"`
+	ChecksParallel bool              `mapstructure:"checks-parallel" json:"checks-parallel"`
+	GlobalAlert    Alert             `mapstructure:"global-alert" json:"global-alert"`
+	GlobalAlerts   []Alert           `mapstructure:"global-alerts" json:"global-alerts,omitempty"`
+	StateDir       string            `mapstructure:"state-dir" json:"state-dir,omitempty"`
+	AlertWorkers   int               `mapstructure:"alert-workers" json:"alert-workers,omitempty"`
+	AlertQueue     int               `mapstructure:"alert-queue" json:"alert-queue,omitempty"`
+	AlertFlush     string            `mapstructure:"alert-flush-timeout" json:"alert-flush-timeout,omitempty"`
+	FloodThreshold int               `mapstructure:"flood-threshold" json:"flood-threshold,omitempty"`
+	SSHConfig      string            `mapstructure:"ssh-config" json:"ssh-config,omitempty"`
+	SSHKeepalive   string            `mapstructure:"ssh-keepalive" json:"ssh-keepalive,omitempty"`
+	SSHMaxIdle     int               `mapstructure:"ssh-max-idle" json:"ssh-max-idle,omitempty"`
+	OutputOptions  map[string]string `mapstructure:"output-options" json:"output-options,omitempty"`
 }
 
 // Host host block content
@@ -28,26 +39,85 @@ type Host struct {
 	KnownHostInsecure bool     `mapstructure:"insecure" json:"insecure"`
 	Disable           bool     `mapstructure:"disable" json:"disable"`
 	Timeout           string   `mapstructure:"timeout" json:"timeout"`
+	Tags              []string `mapstructure:"tags" json:"tags,omitempty"`
+	Jump              []Jump   `mapstructure:"jump" json:"jump,omitempty"`
+	ProxyJump         string   `mapstructure:"proxy-jump" json:"proxy-jump,omitempty"`
+	KnownHosts        string   `mapstructure:"known-hosts" json:"known-hosts,omitempty"`
+	HostKey           string   `mapstructure:"host-key" json:"host-key,omitempty"`
+	TOFU              bool     `mapstructure:"tofu" json:"tofu,omitempty"`
+	Certificate       string   `mapstructure:"certificate" json:"certificate,omitempty"`
+	PassphraseEnv     string   `mapstructure:"passphrase-env" json:"passphrase-env,omitempty"`
+	PassphraseFile    string   `mapstructure:"passphrase-file" json:"passphrase-file,omitempty"`
+	PassphraseCommand string   `mapstructure:"passphrase-command" json:"passphrase-command,omitempty"`
+	Become            string   `mapstructure:"become" json:"become,omitempty"`
+	BecomeUser        string   `mapstructure:"become-user" json:"become-user,omitempty"`
+	BecomePassword    string   `mapstructure:"become-password" json:"become-password,omitempty"`
+	ChecksTimeout     string   `mapstructure:"checks-timeout" json:"checks-timeout,omitempty"`
+	Transport         string   `mapstructure:"transport" json:"transport,omitempty"`
+	Exec              string   `mapstructure:"exec" json:"exec,omitempty"`
+}
+
+// Jump host jump host content
+type Jump struct {
+	Host     string `mapstructure:"host" json:"host"`
+	Port     string `mapstructure:"port" json:"port,omitempty"`
+	User     string `mapstructure:"user" json:"user,omitempty"`
+	Password string `mapstructure:"password" json:"password,omitempty"`
+	Keyfile  string `mapstructure:"keyfile" json:"keyfile,omitempty"`
 }
 
 // Profile profile block content
 type Profile struct {
-	Name   string   `mapstructure:"name" json:"name"`
-	Checks []Check  `mapstructure:"checks" json:"checks"`
-	Alerts []Alert  `mapstructure:"alerts" json:"alerts"`
-	Extend []string `mapstructure:"extend" json:"extend"`
+	Name       string       `mapstructure:"name" json:"name"`
+	Checks     []Check      `mapstructure:"checks" json:"checks"`
+	Alerts     []Alert      `mapstructure:"alerts" json:"alerts"`
+	Extend     []string     `mapstructure:"extend" json:"extend"`
+	Escalation []Escalation `mapstructure:"escalation" json:"escalation,omitempty"`
+}
+
+// Escalation profile escalation tier content
+type Escalation struct {
+	After  int     `mapstructure:"after" json:"after"`
+	Alerts []Alert `mapstructure:"alerts" json:"alerts"`
 }
 
 // Check profile check block content
 type Check struct {
-	Type    string            `mapstructure:"type" json:"type"`
-	Options map[string]string `mapstructure:"options" json:"options"`
-	Disable bool              `mapstructure:"disable" json:"disable"`
+	Type          string            `mapstructure:"type" json:"type"`
+	Options       map[string]string `mapstructure:"options" json:"options"`
+	Disable       bool              `mapstructure:"disable" json:"disable"`
+	Severity      string            `mapstructure:"severity" json:"severity,omitempty"`
+	Retries       int               `mapstructure:"retries" json:"retries,omitempty"`
+	RetryInterval string            `mapstructure:"retry-interval" json:"retry-interval,omitempty"`
+	Threshold     string            `mapstructure:"threshold" json:"threshold,omitempty"`
+	Flapping      string            `mapstructure:"flapping" json:"flapping,omitempty"`
+	Become        string            `mapstructure:"become" json:"become,omitempty"`
+	Timeout       string            `mapstructure:"timeout" json:"timeout,omitempty"`
 }
 
 // Alert profile alert block content
 type Alert struct {
-	Type    string            `mapstructure:"type" json:"type"`
-	Options map[string]string `mapstructure:"options" json:"options"`
-	Disable bool              `mapstructure:"disable" json:"disable"`
+	Type      string            `mapstructure:"type" json:"type"`
+	Options   map[string]string `mapstructure:"options" json:"options"`
+	Disable   bool              `mapstructure:"disable" json:"disable"`
+	Timeout   string            `mapstructure:"timeout" json:"timeout,omitempty"`
+	Retries   int               `mapstructure:"retries" json:"retries,omitempty"`
+	Fallback  *Alert            `mapstructure:"fallback" json:"fallback,omitempty"`
+	RateLimit string            `mapstructure:"rate-limit" json:"rate-limit,omitempty"`
+	On        []string          `mapstructure:"notify-on" json:"notify-on,omitempty"`
+	Checks    []string          `mapstructure:"checks" json:"checks,omitempty"`
+	Tags      []string          `mapstructure:"tags" json:"tags,omitempty"`
+}
+
+// Maintenance maintenance window block content
+type Maintenance struct {
+	Name     string   `mapstructure:"name" json:"name"`
+	Start    string   `mapstructure:"start" json:"start,omitempty"`
+	End      string   `mapstructure:"end" json:"end,omitempty"`
+	Cron     string   `mapstructure:"cron" json:"cron,omitempty"`
+	Duration string   `mapstructure:"duration" json:"duration,omitempty"`
+	Hosts    []string `mapstructure:"hosts" json:"hosts,omitempty"`
+	Tags     []string `mapstructure:"tags" json:"tags,omitempty"`
+	Checks   []string `mapstructure:"checks" json:"checks,omitempty"`
+	Reason   string   `mapstructure:"reason" json:"reason,omitempty"`
 }
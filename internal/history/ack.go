@@ -0,0 +1,174 @@
+// Copyright (c) 2021 deadc0de6
+
+package history
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/deadc0de6/checkah/internal/state"
+	log "github.com/sirupsen/logrus"
+)
+
+// Ack an acknowledged problem, the failing
+// check of a host identified by its description
+type Ack struct {
+	Host    string    `json:"host"`
+	Check   string    `json:"check"`
+	Comment string    `json:"comment,omitempty"`
+	User    string    `json:"user,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// match matches the acknowledged check only
+func (a *Ack) match(host string, description string) bool {
+	return a.Host == host && a.Check == description
+}
+
+// String returns a description of the ack
+func (a *Ack) String() string {
+	s := "acknowledged"
+	if len(a.User) > 0 {
+		s += fmt.Sprintf(" by %s", a.User)
+	}
+	if len(a.Comment) > 0 {
+		s += fmt.Sprintf(": %s", a.Comment)
+	}
+	return s
+}
+
+// Acks the acknowledged problems
+type Acks struct {
+	path string
+	acks []*Ack
+	// the changes merged with the file on save
+	added   []*Ack
+	cleared []*Ack
+	mut     *sync.Mutex
+	dirty   bool
+}
+
+// Add acknowledges a problem
+func (a *Acks) Add(ack *Ack) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	a.acks = append(a.acks, ack)
+	a.added = append(a.added, ack)
+	a.dirty = true
+}
+
+// without returns the acks not matching any of the others
+func without(acks []*Ack, others []*Ack) []*Ack {
+	var res []*Ack
+	for _, ack := range acks {
+		found := false
+		for _, o := range others {
+			if ack.match(o.Host, o.Check) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			res = append(res, ack)
+		}
+	}
+	return res
+}
+
+// Match returns the ack for this check, nil if none
+func (a *Acks) Match(host string, description string) *Ack {
+	if a == nil {
+		return nil
+	}
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	for _, ack := range a.acks {
+		if ack.match(host, description) {
+			return ack
+		}
+	}
+	return nil
+}
+
+// Clear removes the ack of a check that recovered
+func (a *Acks) Clear(host string, description string) {
+	if a == nil {
+		return
+	}
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	var acks []*Ack
+	for _, ack := range a.acks {
+		if ack.match(host, description) {
+			log.Debugf("clearing ack for %s on %s", description, host)
+			a.cleared = append(a.cleared, ack)
+			a.dirty = true
+			continue
+		}
+		acks = append(acks, ack)
+	}
+	a.acks = acks
+	a.added = without(a.added, a.cleared)
+}
+
+// Save writes the acks to disk, merging the changes with the
+// acks written by other processes since they were loaded
+func (a *Acks) Save() error {
+	if a == nil {
+		return nil
+	}
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	if !a.dirty {
+		return nil
+	}
+
+	unlock, err := state.Lock(a.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var acks []*Ack
+	err = state.Load(a.path, &acks)
+	if state.IsCorrupt(err) {
+		log.Errorf("ignoring corrupt acks %s: %v", a.path, err)
+		acks = nil
+	} else if err != nil {
+		return err
+	}
+	acks = without(acks, a.cleared)
+	acks = append(without(acks, a.added), a.added...)
+	if acks == nil {
+		acks = []*Ack{}
+	}
+	err = state.Save(a.path, acks)
+	if err != nil {
+		return err
+	}
+	a.acks = acks
+	a.added = nil
+	a.cleared = nil
+	a.dirty = false
+	return nil
+}
+
+// LoadAcks loads the acks from disk
+func LoadAcks(path string) (*Acks, error) {
+	var acks []*Ack
+	err := state.Load(path, &acks)
+	if state.IsCorrupt(err) {
+		log.Errorf("ignoring corrupt acks %s: %v", path, err)
+		acks = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	a := &Acks{
+		path: path,
+		acks: acks,
+		mut:  &sync.Mutex{},
+	}
+	return a, nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2021 deadc0de6
+
+package history
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func ackKeys(t *testing.T, path string) []string {
+	t.Helper()
+	acks, err := LoadAcks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, ack := range acks.acks {
+		keys = append(keys, ack.Host+"/"+ack.Check)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestAcksSaveMerges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acks.json")
+	newAck := func(host string, check string) *Ack {
+		return &Ack{Host: host, Check: check, Time: time.Now()}
+	}
+
+	first, err := LoadAcks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Add(newAck("web1", "disk"))
+	first.Add(newAck("web2", "load"))
+	if err = first.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a run loads the acks
+	run, err := LoadAcks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the cli acks a check during the run
+	cli, err := LoadAcks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.Add(newAck("web3", "memory"))
+	if err = cli.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the run clears a recovered check and saves
+	run.Clear("web1", "disk")
+	if err = run.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := ackKeys(t, path)
+	want := []string{"web2/load", "web3/memory"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("acks = %v, want %v", got, want)
+	}
+
+	// acking twice keeps a single ack
+	again, err := LoadAcks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again.Add(newAck("web2", "load"))
+	if err = again.Save(); err != nil {
+		t.Fatal(err)
+	}
+	got = ackKeys(t, path)
+	if len(got) != 2 {
+		t.Errorf("acks = %v, want %v", got, want)
+	}
+}
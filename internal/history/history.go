@@ -0,0 +1,184 @@
+// Copyright (c) 2021 deadc0de6
+
+package history
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/deadc0de6/checkah/internal/state"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	maxAge = 30 * 24 * time.Hour
+	// MaxResults the number of results kept per check
+	MaxResults = 20
+)
+
+// Record the state of a check across runs
+type Record struct {
+	Host        string    `json:"host,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Failures    int       `json:"failures"`
+	LastRun     time.Time `json:"last-run"`
+	// consecutive failures when the failure was last notified
+	Notified int `json:"notified,omitempty"`
+	// last results, most recent last, true on failure
+	Results []bool `json:"results,omitempty"`
+}
+
+func (r *Record) last(count int) []bool {
+	if count > len(r.Results) {
+		count = len(r.Results)
+	}
+	return r.Results[len(r.Results)-count:]
+}
+
+// FailedIn returns the number of failures in the last count runs
+func (r *Record) FailedIn(count int) int {
+	failed := 0
+	for _, f := range r.last(count) {
+		if f {
+			failed++
+		}
+	}
+	return failed
+}
+
+// ChangesIn returns the number of state changes in the last count runs
+func (r *Record) ChangesIn(count int) int {
+	results := r.last(count)
+	changes := 0
+	for i := 1; i < len(results); i++ {
+		if results[i] != results[i-1] {
+			changes++
+		}
+	}
+	return changes
+}
+
+// History the records of all checks
+type History struct {
+	path    string
+	records map[string]*Record
+	mut     *sync.Mutex
+}
+
+func key(host string, check string) string {
+	return fmt.Sprintf("%s/%s", host, check)
+}
+
+// Update records a new result for a check
+// and returns the updated record
+func (h *History) Update(host string, name string, check string, failed bool) Record {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	k := key(host, check)
+	r, ok := h.records[k]
+	if !ok {
+		r = &Record{}
+		h.records[k] = r
+	}
+	r.Host = host
+	r.Name = name
+	r.Description = check
+
+	if failed {
+		r.Failures++
+	} else {
+		r.Failures = 0
+	}
+	r.LastRun = time.Now()
+
+	r.Results = append(r.Results, failed)
+	if len(r.Results) > MaxResults {
+		r.Results = r.Results[len(r.Results)-MaxResults:]
+	}
+
+	// copy the results
+	c := *r
+	c.Results = append([]bool{}, r.Results...)
+	return c
+}
+
+// SetNotified records the number of consecutive failures
+// when the failure was notified, 0 once the recovery is notified
+func (h *History) SetNotified(host string, check string, failures int) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	r, ok := h.records[key(host, check)]
+	if !ok {
+		return
+	}
+	r.Notified = failures
+}
+
+// Failing returns the failing checks matching the host
+// glob and the check name or description glob
+func (h *History) Failing(host string, check string) []Record {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	var records []Record
+	for _, r := range h.records {
+		if r.Failures < 1 {
+			continue
+		}
+		ok, _ := path.Match(host, r.Host)
+		if !ok {
+			continue
+		}
+		okName, _ := path.Match(check, r.Name)
+		okDesc, _ := path.Match(check, r.Description)
+		if okName || okDesc {
+			records = append(records, *r)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return key(records[i].Host, records[i].Description) < key(records[j].Host, records[j].Description)
+	})
+	return records
+}
+
+// Save writes the history to disk
+func (h *History) Save() error {
+	if h == nil {
+		return nil
+	}
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	// forget about checks that are gone
+	for k, r := range h.records {
+		if time.Since(r.LastRun) > maxAge {
+			delete(h.records, k)
+		}
+	}
+	return state.Save(h.path, h.records)
+}
+
+// Load loads the history from disk
+func Load(path string) (*History, error) {
+	records := make(map[string]*Record)
+	err := state.Load(path, &records)
+	if state.IsCorrupt(err) {
+		log.Errorf("ignoring corrupt history %s: %v", path, err)
+		records = make(map[string]*Record)
+	} else if err != nil {
+		return nil, err
+	}
+
+	h := &History{
+		path:    path,
+		records: records,
+		mut:     &sync.Mutex{},
+	}
+	return h, nil
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2021 deadc0de6
+
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	journalSocket = "/run/systemd/journal/socket"
+)
+
+var (
+	journalField = regexp.MustCompile("^[A-Z0-9][A-Z0-9_]*$")
+)
+
+// Journal sends entries to journald with its native protocol
+type Journal struct {
+	path string
+	conn net.Conn
+	mut  *sync.Mutex
+}
+
+func appendField(buf *bytes.Buffer, key string, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(buf, "%s=%s\n", key, value)
+		return
+	}
+	// values with newlines are length prefixed
+	buf.WriteString(key)
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// Send sends an entry, fields names must be uppercase
+// letters, digits and underscores, it gives up when ctx is done
+func (j *Journal) Send(ctx context.Context, priority int, fields map[string]string, msg string) error {
+	var buf bytes.Buffer
+	appendField(&buf, "MESSAGE", msg)
+	appendField(&buf, "PRIORITY", fmt.Sprintf("%d", priority))
+	appendField(&buf, "SYSLOG_IDENTIFIER", "checkah")
+	for k, v := range fields {
+		if !journalField.MatchString(k) {
+			return fmt.Errorf("bad journal field name: %s", k)
+		}
+		appendField(&buf, k, v)
+	}
+
+	j.mut.Lock()
+	defer j.mut.Unlock()
+
+	if j.conn == nil {
+		dialer := &net.Dialer{Deadline: deadline(ctx)}
+		conn, err := dialer.DialContext(ctx, "unixgram", j.path)
+		if err != nil {
+			return err
+		}
+		j.conn = conn
+	}
+
+	_ = j.conn.SetWriteDeadline(deadline(ctx))
+	_, err := j.conn.Write(buf.Bytes())
+	if err != nil {
+		j.conn.Close()
+		j.conn = nil
+	}
+	return err
+}
+
+// Close closes the connection
+func (j *Journal) Close() error {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	if j.conn == nil {
+		return nil
+	}
+	err := j.conn.Close()
+	j.conn = nil
+	return err
+}
+
+// NewJournal creates a journald sink, path is
+// the journal socket (default to the systemd one)
+func NewJournal(path string) (*Journal, error) {
+	if len(path) < 1 {
+		path = journalSocket
+	}
+	j := &Journal{
+		path: path,
+		mut:  &sync.Mutex{},
+	}
+	return j, nil
+}
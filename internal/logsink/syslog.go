@@ -0,0 +1,231 @@
+// Copyright (c) 2021 deadc0de6
+
+package logsink
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syslog severities
+const (
+	SeverityCritical = 2
+	SeverityError    = 3
+	SeverityWarning  = 4
+	SeverityInfo     = 6
+)
+
+const (
+	dialTimeout = 5 * time.Second
+	// structured data id (private enterprise number for documentation)
+	sdID = "checkah@32473"
+)
+
+var (
+	facilities = map[string]int{
+		"kern":     0,
+		"user":     1,
+		"mail":     2,
+		"daemon":   3,
+		"auth":     4,
+		"syslog":   5,
+		"lpr":      6,
+		"news":     7,
+		"uucp":     8,
+		"cron":     9,
+		"authpriv": 10,
+		"ftp":      11,
+		"local0":   16,
+		"local1":   17,
+		"local2":   18,
+		"local3":   19,
+		"local4":   20,
+		"local5":   21,
+		"local6":   22,
+		"local7":   23,
+	}
+	localSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+)
+
+// Syslog sends RFC 3164 messages to the local syslog
+// socket, like log/syslog, or RFC 5424 messages to a
+// remote server over UDP/TCP
+type Syslog struct {
+	network  string
+	address  string
+	facility int
+	tag      string
+	hostname string
+	conn     net.Conn
+	// the local socket is a stream one
+	stream bool
+	mut    *sync.Mutex
+}
+
+// deadline returns the deadline of an operation,
+// the one of ctx if sooner than the default one
+func deadline(ctx context.Context) time.Time {
+	d := time.Now().Add(dialTimeout)
+	ctxd, ok := ctx.Deadline()
+	if ok && ctxd.Before(d) {
+		return ctxd
+	}
+	return d
+}
+
+func (s *Syslog) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Deadline: deadline(ctx)}
+	if len(s.network) > 0 {
+		return dialer.DialContext(ctx, s.network, s.address)
+	}
+
+	// local socket
+	var err error
+	for _, path := range localSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			var conn net.Conn
+			conn, err = dialer.DialContext(ctx, network, path)
+			if err == nil {
+				s.stream = network == "unix"
+				return conn, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no local syslog socket: %v", err)
+}
+
+// escape a structured data parameter value
+func escapeSD(value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	return r.Replace(value)
+}
+
+func (s *Syslog) format(severity int, msgid string, data map[string]string, msg string) string {
+	sd := "-"
+	if len(data) > 0 {
+		var keys []string
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var params []string
+		for _, k := range keys {
+			params = append(params, fmt.Sprintf("%s=\"%s\"", k, escapeSD(data[k])))
+		}
+		sd = fmt.Sprintf("[%s %s]", sdID, strings.Join(params, " "))
+	}
+	if len(msgid) < 1 {
+		msgid = "-"
+	}
+
+	// newlines would split the message
+	msg = strings.ReplaceAll(msg, "\n", " ")
+	pri := s.facility*8 + severity
+	if len(s.network) < 1 {
+		// the local daemons expect the BSD format, the
+		// structured data is already in the message
+		ts := time.Now().Format(time.Stamp)
+		return fmt.Sprintf("<%d>%s %s[%d]: %s", pri, ts, s.tag, os.Getpid(), msg)
+	}
+	ts := time.Now().Format(time.RFC3339Nano)
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s", pri, ts, s.hostname, s.tag, os.Getpid(), msgid, sd, msg)
+}
+
+func (s *Syslog) write(ctx context.Context, line string) error {
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	// octet counting framing over TCP, newline
+	// terminated on a local stream socket
+	if s.network == "tcp" {
+		line = fmt.Sprintf("%d %s", len(line), line)
+	} else if s.stream {
+		line += "\n"
+	}
+	_ = s.conn.SetWriteDeadline(deadline(ctx))
+	_, err := s.conn.Write([]byte(line))
+	if err != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+// Send sends a message, data is sent as structured data,
+// it gives up when ctx is done
+func (s *Syslog) Send(ctx context.Context, severity int, msgid string, data map[string]string, msg string) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	line := s.format(severity, msgid, data, msg)
+	err := s.write(ctx, line)
+	if err != nil && ctx.Err() == nil {
+		// reconnect once
+		err = s.write(ctx, line)
+	}
+	return err
+}
+
+// Close closes the connection
+func (s *Syslog) Close() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// NewSyslog creates a syslog sink, address is empty
+// for the local socket or <udp|tcp>://<host>:<port>
+func NewSyslog(address string, facility string, tag string) (*Syslog, error) {
+	s := &Syslog{
+		facility: facilities["daemon"],
+		tag:      "checkah",
+		hostname: "-",
+		mut:      &sync.Mutex{},
+	}
+
+	if len(address) > 0 {
+		fields := strings.SplitN(address, "://", 2)
+		if len(fields) != 2 || (fields[0] != "udp" && fields[0] != "tcp") {
+			return nil, fmt.Errorf("bad address \"%s\", expecting <udp|tcp>://<host>:<port>", address)
+		}
+		s.network = fields[0]
+		s.address = fields[1]
+		if _, _, err := net.SplitHostPort(s.address); err != nil {
+			s.address = net.JoinHostPort(s.address, "514")
+		}
+	}
+
+	if len(facility) > 0 {
+		f, ok := facilities[strings.ToLower(facility)]
+		if !ok {
+			return nil, fmt.Errorf("no such facility: %s", facility)
+		}
+		s.facility = f
+	}
+
+	if len(tag) > 0 {
+		s.tag = tag
+	}
+
+	hostname, err := os.Hostname()
+	if err == nil && len(hostname) > 0 {
+		s.hostname = hostname
+	}
+	return s, nil
+}
@@ -25,16 +25,30 @@ func (o *Influxdb) StackErr(key string, pre string, content string) {
 	o.push(key, pre, content)
 }
 
+// StackTimeout add a new check that timed out
+func (o *Influxdb) StackTimeout(key string, pre string, content string) {
+	o.push(key, pre, content)
+}
+
 // StackOk add a new success
 func (o *Influxdb) StackOk(key string, pre string, content string) {
 	o.push(key, pre, content)
 }
 
+// StackMuted add a new error that does not notify
+func (o *Influxdb) StackMuted(key string, pre string, content string, _ string) {
+	o.push(key, pre, content)
+}
+
 // Flush closes this output
 func (o *Influxdb) Flush(string) {
 	o.client.Close()
 }
 
+// Close does nothing
+func (o *Influxdb) Close() {
+}
+
 // Push pushes output
 // https://www.influxdata.com/blog/getting-started-with-the-influxdb-go-client/
 // https://docs.influxdata.com/influxdb/v1.8/write_protocols/line_protocol_tutorial/
@@ -0,0 +1,75 @@
+// Copyright (c) 2021 deadc0de6
+
+package output
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/deadc0de6/checkah/internal/logsink"
+	log "github.com/sirupsen/logrus"
+)
+
+// Journald output struct
+type Journald struct {
+	names
+	sink *logsink.Journal
+}
+
+func (o *Journald) send(priority int, status string, key string, pre string, content string) {
+	fields := map[string]string{
+		"CHECKAH_HOST":   o.name(key),
+		"CHECKAH_CHECK":  pre,
+		"CHECKAH_STATUS": status,
+		"CHECKAH_VALUE":  content,
+	}
+	msg := fmt.Sprintf("%s - %s: %s", key, pre, content)
+	err := o.sink.Send(context.Background(), priority, fields, msg)
+	if err != nil {
+		log.Errorf("journald output: %v", err)
+	}
+}
+
+// StackErr add a new error
+func (o *Journald) StackErr(key string, pre string, content string) {
+	o.send(logsink.SeverityError, "error", key, pre, content)
+}
+
+// StackTimeout add a new check that timed out
+func (o *Journald) StackTimeout(key string, pre string, content string) {
+	o.send(logsink.SeverityError, "timeout", key, pre, content)
+}
+
+// StackOk add a new success
+func (o *Journald) StackOk(key string, pre string, content string) {
+	o.send(logsink.SeverityInfo, "ok", key, pre, content)
+}
+
+// StackMuted add a new error that does not notify
+func (o *Journald) StackMuted(key string, pre string, content string, reason string) {
+	o.send(logsink.SeverityWarning, reason, key, pre, content)
+}
+
+// Flush flush output
+func (o *Journald) Flush(string) {
+}
+
+// Close closes the connection
+func (o *Journald) Close() {
+	err := o.sink.Close()
+	if err != nil {
+		log.Errorf("journald output: %v", err)
+	}
+}
+
+// NewJournald new instance
+func NewJournald(options map[string]string) (*Journald, error) {
+	sink, err := logsink.NewJournal(options["socket"])
+	if err != nil {
+		return nil, err
+	}
+	o := &Journald{
+		sink: sink,
+	}
+	return o, nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2021 deadc0de6
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// a check entry
+type jsonEntry struct {
+	Check  string `json:"check"`
+	Status string `json:"status"`
+	Muted  string `json:"muted,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// JSON prints one JSON object per host
+type JSON struct {
+	names
+	output map[string][]*jsonEntry
+	mut    *sync.Mutex
+}
+
+func (o *JSON) stack(key string, entry *jsonEntry) {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+	o.output[key] = append(o.output[key], entry)
+}
+
+// StackErr add a new error
+func (o *JSON) StackErr(key string, pre string, content string) {
+	o.stack(key, &jsonEntry{
+		Check:  pre,
+		Status: "error",
+		Error:  content,
+	})
+}
+
+// StackTimeout add a new check that timed out
+func (o *JSON) StackTimeout(key string, pre string, content string) {
+	o.stack(key, &jsonEntry{
+		Check:  pre,
+		Status: "timeout",
+		Error:  content,
+	})
+}
+
+// StackOk add a new success
+func (o *JSON) StackOk(key string, pre string, content string) {
+	o.stack(key, &jsonEntry{
+		Check:  pre,
+		Status: "ok",
+		Value:  content,
+	})
+}
+
+// StackMuted add a new error that does not notify
+func (o *JSON) StackMuted(key string, pre string, content string, reason string) {
+	o.stack(key, &jsonEntry{
+		Check:  pre,
+		Status: "muted",
+		Muted:  reason,
+		Error:  content,
+	})
+}
+
+// Flush flush output
+func (o *JSON) Flush(key string) {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	entries, ok := o.output[key]
+	if !ok {
+		return
+	}
+	host := map[string]interface{}{
+		"host":   o.name(key),
+		"checks": entries,
+	}
+	b, err := json.Marshal(host)
+	if err != nil {
+		return
+	}
+	fmt.Println(string(b))
+}
+
+// Close does nothing
+func (o *JSON) Close() {
+}
+
+// NewJSON new instance
+func NewJSON(_ map[string]string) (*JSON, error) {
+	o := &JSON{
+		output: make(map[string][]*jsonEntry),
+		mut:    &sync.Mutex{},
+	}
+	return o, nil
+}
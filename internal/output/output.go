@@ -1,12 +1,51 @@
 package output
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
 
 // Output struct
 type Output interface {
 	StackErr(string, string, string)
+	StackTimeout(string, string, string)
 	StackOk(string, string, string)
+	StackMuted(string, string, string, string)
 	Flush(string)
+	Close()
+}
+
+// Named an output that needs the host name apart
+// from the output key, set before its checks are stacked
+type Named interface {
+	SetName(key string, name string)
+}
+
+// names the host names of the output keys
+type names struct {
+	names map[string]string
+	mut   sync.Mutex
+}
+
+// SetName sets the host name of an output key
+func (n *names) SetName(key string, name string) {
+	n.mut.Lock()
+	defer n.mut.Unlock()
+	if n.names == nil {
+		n.names = make(map[string]string)
+	}
+	n.names[key] = name
+}
+
+// name returns the host name of an output key, the key if not set
+func (n *names) name(key string) string {
+	n.mut.Lock()
+	defer n.mut.Unlock()
+	name, ok := n.names[key]
+	if !ok {
+		return key
+	}
+	return name
 }
 
 // GetOutput returns an output instance
@@ -14,8 +53,14 @@ func GetOutput(name string, options map[string]string) (Output, error) {
 	switch name {
 	case "stdout":
 		return NewStdout(options)
+	case "json":
+		return NewJSON(options)
 	case "influxdb":
 		return NewInfluxdb(options)
+	case "syslog":
+		return NewSyslog(options)
+	case "journald":
+		return NewJournald(options)
 	}
 	return nil, fmt.Errorf("no such output: %s", name)
 }
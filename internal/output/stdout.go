@@ -23,6 +23,11 @@ func outputErr(pre string, str string) string {
 	return pre + col.Sprintln(str)
 }
 
+func outputMuted(pre string, str string) string {
+	col := color.New(color.FgYellow)
+	return pre + col.Sprintln(str)
+}
+
 func checkPre(ok bool) string {
 	pre := "ok"
 	col := color.New(color.FgGreen)
@@ -60,6 +65,20 @@ func (o *Stdout) StackErr(key string, pre string, content string) {
 	o.output[key] = v
 }
 
+// StackTimeout add a new check that timed out
+func (o *Stdout) StackTimeout(key string, pre string, content string) {
+	o.mut.Lock()
+	v := o.getOrAdd(key)
+	defer o.mut.Unlock()
+
+	// append timeout
+	col := color.New(color.FgRed)
+	v += "  "
+	v += fmt.Sprintf("[%s]", col.Sprint("TIMEOUT"))
+	v += outputErr(fmt.Sprintf(" %s: ", pre), content)
+	o.output[key] = v
+}
+
 // StackOk add a new success
 func (o *Stdout) StackOk(key string, pre string, content string) {
 	o.mut.Lock()
@@ -73,6 +92,20 @@ func (o *Stdout) StackOk(key string, pre string, content string) {
 	o.output[key] = v
 }
 
+// StackMuted add a new error that does not notify
+func (o *Stdout) StackMuted(key string, pre string, content string, reason string) {
+	o.mut.Lock()
+	v := o.getOrAdd(key)
+	defer o.mut.Unlock()
+
+	// append muted error
+	col := color.New(color.FgYellow)
+	v += "  "
+	v += fmt.Sprintf("[%s]", col.Sprint(reason))
+	v += outputMuted(fmt.Sprintf(" %s: ", pre), content)
+	o.output[key] = v
+}
+
 // Flush flush output
 func (o *Stdout) Flush(key string) {
 	o.mut.Lock()
@@ -84,6 +117,10 @@ func (o *Stdout) Flush(key string) {
 	}
 }
 
+// Close does nothing
+func (o *Stdout) Close() {
+}
+
 // NewStdout new instance
 func NewStdout(_ map[string]string) (*Stdout, error) {
 	o := &Stdout{
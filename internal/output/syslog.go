@@ -0,0 +1,74 @@
+// Copyright (c) 2021 deadc0de6
+
+package output
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/deadc0de6/checkah/internal/logsink"
+	log "github.com/sirupsen/logrus"
+)
+
+// Syslog output struct
+type Syslog struct {
+	names
+	sink *logsink.Syslog
+}
+
+func (o *Syslog) send(severity int, status string, key string, pre string, content string) {
+	data := map[string]string{
+		"host":   o.name(key),
+		"check":  pre,
+		"status": status,
+	}
+	msg := fmt.Sprintf("%s - %s: %s", key, pre, content)
+	err := o.sink.Send(context.Background(), severity, "result", data, msg)
+	if err != nil {
+		log.Errorf("syslog output: %v", err)
+	}
+}
+
+// StackErr add a new error
+func (o *Syslog) StackErr(key string, pre string, content string) {
+	o.send(logsink.SeverityError, "error", key, pre, content)
+}
+
+// StackTimeout add a new check that timed out
+func (o *Syslog) StackTimeout(key string, pre string, content string) {
+	o.send(logsink.SeverityError, "timeout", key, pre, content)
+}
+
+// StackOk add a new success
+func (o *Syslog) StackOk(key string, pre string, content string) {
+	o.send(logsink.SeverityInfo, "ok", key, pre, content)
+}
+
+// StackMuted add a new error that does not notify
+func (o *Syslog) StackMuted(key string, pre string, content string, reason string) {
+	o.send(logsink.SeverityWarning, reason, key, pre, content)
+}
+
+// Flush flush output
+func (o *Syslog) Flush(string) {
+}
+
+// Close closes the connection
+func (o *Syslog) Close() {
+	err := o.sink.Close()
+	if err != nil {
+		log.Errorf("syslog output: %v", err)
+	}
+}
+
+// NewSyslog new instance
+func NewSyslog(options map[string]string) (*Syslog, error) {
+	sink, err := logsink.NewSyslog(options["address"], options["facility"], options["tag"])
+	if err != nil {
+		return nil, err
+	}
+	o := &Syslog{
+		sink: sink,
+	}
+	return o, nil
+}
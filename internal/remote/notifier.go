@@ -0,0 +1,170 @@
+// Copyright (c) 2021 deadc0de6
+
+package remote
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/deadc0de6/checkah/internal/alert"
+	"github.com/deadc0de6/checkah/internal/check"
+	"github.com/deadc0de6/checkah/internal/history"
+	"github.com/deadc0de6/checkah/internal/silence"
+	log "github.com/sirupsen/logrus"
+)
+
+// a host down notification held for flood protection
+type held struct {
+	event  *alert.Event
+	alerts []alert.Alert
+}
+
+// Notifier sits between the checks and the alerts
+type Notifier struct {
+	disp           *alert.Dispatcher
+	floodThreshold int
+	silences       *silence.Silences
+	history        *history.History
+	acks           *history.Acks
+	held           []*held
+	mut            *sync.Mutex
+}
+
+// silenced returns the rule silencing this check, nil if none
+func (n *Notifier) silenced(remote *Remote, name string, description string) *silence.Rule {
+	return n.silences.Match(remote.Name, remote.Tags, name, description)
+}
+
+// acked returns the ack for this check, nil if none
+func (n *Notifier) acked(remote *Remote, description string) *history.Ack {
+	return n.acks.Match(remote.Name, description)
+}
+
+// record records a check result in the history,
+// a check that recovered is not acknowledged anymore
+func (n *Notifier) record(remote *Remote, name string, description string, failed bool) history.Record {
+	if !failed {
+		n.acks.Clear(remote.Name, description)
+	}
+	return n.history.Update(remote.Name, name, description, failed)
+}
+
+// notified records the failure of a check was notified
+func (n *Notifier) notified(remote *Remote, description string, failures int) {
+	n.history.SetNotified(remote.Name, description, failures)
+}
+
+// recovered notifies the recovery of a check whose failure
+// was notified, to the alerts that were notified
+func (n *Notifier) recovered(remote *Remote, hc *HostCheck, res *check.Result, record history.Record) {
+	n.notified(remote, res.Description, 0)
+	if n.silenced(remote, res.Name, res.Description) != nil {
+		return
+	}
+
+	msg := fmt.Sprintf("%s: recovered (%s)", res.Description, res.Value)
+	event := alert.NewEvent(remote.Name, res.Name, res.Description, msg, hc.Severity)
+	event.Status = alert.StatusRecovery
+	event.Tags = remote.Tags
+	n.notify(event, escalate(remote.Alerts, hc.Tiers, record.Notified))
+}
+
+// trusted records the host key was accepted, a host key
+// change that was notified is notified as recovered
+func (n *Notifier) trusted(remote *Remote) {
+	record := n.record(remote, hostKeyName, hostKeyDescription, false)
+	if record.Notified < 1 {
+		return
+	}
+	n.notified(remote, hostKeyDescription, 0)
+	if n.silenced(remote, hostKeyName, hostKeyDescription) != nil {
+		return
+	}
+
+	msg := fmt.Sprintf("host %s key is trusted", remote.Name)
+	event := alert.NewEvent(remote.Name, hostKeyName, hostKeyDescription, msg, alert.SeverityCritical)
+	event.Status = alert.StatusRecovery
+	event.Tags = remote.Tags
+	n.notify(event, escalate(remote.Alerts, remote.Tiers, record.Notified))
+}
+
+// notify notifies each alert once
+func (n *Notifier) notify(event *alert.Event, alerts []alert.Alert) {
+	seen := make(map[alert.Alert]bool)
+	for _, a := range alerts {
+		if seen[a] {
+			continue
+		}
+		seen[a] = true
+		log.Debugf("queue notification for %s", a.GetDescription())
+		n.disp.Dispatch(a, event)
+	}
+}
+
+// hostDown notifies a host is down, held until
+// the end of the run if flood protection is enabled
+func (n *Notifier) hostDown(event *alert.Event, alerts []alert.Alert) {
+	if n.floodThreshold < 1 {
+		n.notify(event, alerts)
+		return
+	}
+
+	n.mut.Lock()
+	defer n.mut.Unlock()
+	n.held = append(n.held, &held{
+		event:  event,
+		alerts: alerts,
+	})
+}
+
+// Flush releases the held notifications, collapsing them
+// into a single mass outage notification when above the threshold
+func (n *Notifier) Flush() {
+	n.mut.Lock()
+	hs := n.held
+	n.held = nil
+	n.mut.Unlock()
+
+	if len(hs) <= n.floodThreshold {
+		for _, h := range hs {
+			n.notify(h.event, h.alerts)
+		}
+		return
+	}
+
+	// each alert is notified once
+	var hosts []string
+	var alerts []alert.Alert
+	seen := make(map[alert.Alert]bool)
+	for _, h := range hs {
+		hosts = append(hosts, h.event.Host)
+		for _, a := range h.alerts {
+			if seen[a] {
+				continue
+			}
+			seen[a] = true
+			alerts = append(alerts, a)
+		}
+	}
+	sort.Strings(hosts)
+
+	log.Debugf("mass outage: %d hosts down", len(hosts))
+	msg := fmt.Sprintf("mass outage: %d host(s) not reachable: %s", len(hosts), strings.Join(hosts, ", "))
+	n.notify(alert.NewEvent("", "reachable", "mass outage", msg, alert.SeverityCritical), alerts)
+}
+
+// NewNotifier creates a notifier, flood protection
+// is disabled if floodThreshold is below 1
+func NewNotifier(disp *alert.Dispatcher, floodThreshold int, silences *silence.Silences, hist *history.History, acks *history.Acks) *Notifier {
+	n := &Notifier{
+		disp:           disp,
+		floodThreshold: floodThreshold,
+		silences:       silences,
+		history:        hist,
+		acks:           acks,
+		mut:            &sync.Mutex{},
+	}
+	return n
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2021 deadc0de6
+
+package remote
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/deadc0de6/checkah/internal/alert"
+)
+
+// fakeAlert records the events it is notified with
+type fakeAlert struct {
+	desc   string
+	mut    sync.Mutex
+	events []*alert.Event
+}
+
+func (a *fakeAlert) Notify(_ context.Context, e *alert.Event) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	a.events = append(a.events, e)
+	return nil
+}
+
+func (a *fakeAlert) GetDescription() string {
+	return a.desc
+}
+
+func (a *fakeAlert) GetOptions() map[string]string {
+	return nil
+}
+
+func (a *fakeAlert) Close() error {
+	return nil
+}
+
+func TestNotifierFlush(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		down      int
+		// events each alert gets
+		want int
+	}{
+		{"disabled", 0, 5, 5},
+		{"below", 3, 2, 2},
+		{"at", 3, 3, 3},
+		{"above", 3, 4, 1},
+		{"far above", 3, 20, 1},
+	}
+	for _, tt := range tests {
+		spool, err := alert.NewSpool(filepath.Join(t.TempDir(), "spool.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		disp := alert.NewDispatcher(1, 100, spool)
+		n := NewNotifier(disp, tt.threshold, nil, nil, nil)
+
+		// every host notifies both, the first one twice
+		a := &fakeAlert{desc: "a"}
+		b := &fakeAlert{desc: "b"}
+		for i := 0; i < tt.down; i++ {
+			host := fmt.Sprintf("host%02d", i)
+			msg := fmt.Sprintf("host %s not reachable", host)
+			event := alert.NewEvent(host, "reachable", "host is reachable", msg, alert.SeverityCritical)
+			n.hostDown(event, []alert.Alert{a, b, a})
+		}
+		n.Flush()
+		err = disp.Close(5 * time.Second)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		for _, f := range []*fakeAlert{a, b} {
+			if len(f.events) != tt.want {
+				t.Errorf("%s: alert %s notified %d time(s), want %d", tt.name, f.desc, len(f.events), tt.want)
+				continue
+			}
+			if tt.want > 1 || tt.down == 1 {
+				continue
+			}
+			e := f.events[0]
+			if e.Description != "mass outage" || !strings.Contains(e.Message, fmt.Sprintf("%d host(s)", tt.down)) {
+				t.Errorf("%s: alert %s got %q, want the mass outage", tt.name, f.desc, e.Message)
+			}
+		}
+	}
+}
@@ -3,14 +3,19 @@
 package remote
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/deadc0de6/checkah/internal/alert"
 	"github.com/deadc0de6/checkah/internal/check"
 	"github.com/deadc0de6/checkah/internal/config"
+	"github.com/deadc0de6/checkah/internal/history"
 	"github.com/deadc0de6/checkah/internal/output"
 	"github.com/deadc0de6/checkah/internal/transport"
 
@@ -19,17 +24,45 @@ import (
 )
 
 const (
-	maxJobs = 4
+	maxJobs       = 4
+	alertTimeout  = "30"
+	retryInterval = "1"
+	checkTimeout  = "60"
+	// the transports
+	transportSSH   = "ssh"
+	transportLocal = "local"
+	transportExec  = "exec"
+	// the pseudo-check of the SSH host key
+	hostKeyName        = "hostkey"
+	hostKeyDescription = "host key is trusted"
 )
 
 var (
 	hostLocalhost = []string{"127.0.0.1", "localhost"}
 )
 
+// a check result with its check
+type hostResult struct {
+	res *check.Result
+	hc  *HostCheck
+}
+
+// Failure a notified check failure
+type Failure struct {
+	Description string
+	Value       string
+	Limit       string
+	Error       string
+}
+
 // HostResult host result struct
 type HostResult struct {
+	Name         string
+	Unreachable  string
+	Failures     []*Failure
 	NbCheckTotal int
 	NbCheckError int
+	NbCheckMuted int
 }
 
 // Remote a remote host to check
@@ -39,28 +72,311 @@ type Remote struct {
 	Port              string
 	User              string
 	Password          string
-	Keyfile           string
-	Checks            []check.Check
+	Keyfiles          []string
+	Certificates      []string
+	Passphrase        *transport.Passphrase
+	KnownHosts        []string
+	HostKeys          []string
+	TOFU              string
+	Checks            []*HostCheck
 	Alerts            []alert.Alert
+	Tiers             []*Tier
 	Timeout           int
+	Tags              []string
+	Jumps             []*transport.SSHOptions
 	KnownHostInsecure bool
-	Disable           bool
+	Become            string
+	BecomeUser        string
+	BecomePassword    string
+	// deadline of all the checks, none if zero
+	ChecksTimeout time.Duration
+	// ssh, local or exec
+	Transport string
+	// the prefix of the exec transport
+	Exec    string
+	Disable bool
+}
+
+// HostCheck a check run on a host
+type HostCheck struct {
+	Check         check.Check
+	Severity      string
+	Tiers         []*Tier
+	Retries       int
+	RetryInterval time.Duration
+	Threshold     *Window
+	Flapping      *Window
+	// sudo, doas, none or empty for the host one
+	Become string
+	// none if zero
+	Timeout time.Duration
+}
+
+// Window "count" occurrences in the last "runs" runs
+type Window struct {
+	Count int
+	Runs  int
+}
+
+// parseWindow parses a "<count>/<runs>" window
+func parseWindow(value string) (*Window, error) {
+	if len(value) < 1 {
+		return nil, nil
+	}
+	fields := strings.Split(value, "/")
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("bad value \"%s\", expecting <count>/<runs>", value)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+	if err != nil {
+		return nil, err
+	}
+	runs, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+	if err != nil {
+		return nil, err
+	}
+	if count < 1 || count > runs || runs > history.MaxResults {
+		return nil, fmt.Errorf("bad value \"%s\", expecting 0 < count <= runs <= %d", value, history.MaxResults)
+	}
+	w := &Window{
+		Count: count,
+		Runs:  runs,
+	}
+	return w, nil
+}
+
+// flapping returns a reason if the check is flapping
+func (hc *HostCheck) flapping(record history.Record) string {
+	if hc.Flapping == nil {
+		return ""
+	}
+	changes := record.ChangesIn(hc.Flapping.Runs)
+	if changes < hc.Flapping.Count {
+		return ""
+	}
+	return fmt.Sprintf("%d state changes in the last %d runs", changes, hc.Flapping.Runs)
+}
+
+// pending returns a reason if the check failed
+// too few times to be notified
+func (hc *HostCheck) pending(record history.Record) string {
+	if hc.Threshold == nil {
+		return ""
+	}
+	failed := record.FailedIn(hc.Threshold.Runs)
+	if failed >= hc.Threshold.Count {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d failures in the last %d runs", failed, hc.Threshold.Count, hc.Threshold.Runs)
+}
+
+// transportFor returns the transport of a check, running its commands
+// with sudo or doas if the check or its host asks for it
+func (remote *Remote) transportFor(trans transport.Transport, hc *HostCheck, becomes map[string]transport.Transport) transport.Transport {
+	method := hc.Become
+	if len(method) < 1 {
+		method = remote.Become
+	}
+	if len(method) < 1 || method == transport.BecomeNone {
+		return trans
+	}
+	t, ok := becomes[method]
+	if ok {
+		return t
+	}
+	b, err := transport.NewBecome(trans, method, remote.BecomeUser, remote.BecomePassword)
+	if err != nil {
+		// validated when creating the remote
+		log.Error(err)
+		return trans
+	}
+	becomes[method] = b
+	return b
+}
+
+// defaultCheckTimeout returns the timeout of the checks without one
+func defaultCheckTimeout() time.Duration {
+	v, _ := strconv.Atoi(checkTimeout)
+	return time.Duration(v) * time.Second
+}
+
+// timedOut returns a timeout result for the check
+func (hc *HostCheck) timedOut(res *check.Result, err error) *check.Result {
+	if res == nil {
+		res = &check.Result{
+			Name:        hc.Check.GetName(),
+			Description: hc.Check.GetDescription(),
+		}
+	}
+	res.Error = err
+	res.Timeout = true
+	return res
+}
+
+// runOnce runs the check within its timeout and the host deadline
+func (hc *HostCheck) runOnce(parent context.Context, trans transport.Transport) *check.Result {
+	if parent.Err() != nil {
+		return hc.timedOut(nil, fmt.Errorf("host checks timed out"))
+	}
+	ctx := parent
+	if hc.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, hc.Timeout)
+		defer cancel()
+	}
+
+	res := hc.Check.Run(ctx, trans)
+	if res.Error == nil || ctx.Err() == nil {
+		return res
+	}
+	if parent.Err() != nil {
+		return hc.timedOut(res, fmt.Errorf("host checks timed out"))
+	}
+	return hc.timedOut(res, fmt.Errorf("timed out after %s", hc.Timeout))
+}
+
+// run runs the check, retrying on failure
+func (hc *HostCheck) run(ctx context.Context, trans transport.Transport) *check.Result {
+	res := hc.runOnce(ctx, trans)
+	for i := 0; i < hc.Retries && res.Error != nil && ctx.Err() == nil; i++ {
+		log.Debugf("retrying check %s (%d/%d): %v", hc.Check.GetDescription(), i+1, hc.Retries, res.Error)
+		select {
+		case <-ctx.Done():
+			// the last failure is reported
+			return res
+		case <-time.After(hc.RetryInterval):
+		}
+		res = hc.runOnce(ctx, trans)
+	}
+	return res
+}
+
+// Tier an escalation tier, its alerts are notified
+// after a number of consecutive failures
+type Tier struct {
+	After  int
+	Alerts []alert.Alert
 }
 
 type profileStruct struct {
-	checks []check.Check
+	checks []*HostCheck
 	alerts []alert.Alert
+	tiers  []*Tier
+}
+
+// escalate returns the alerts to notify after a number of consecutive failures
+func escalate(alerts []alert.Alert, tiers []*Tier, failures int) []alert.Alert {
+	var all []alert.Alert
+	all = append(all, alerts...)
+	for _, t := range tiers {
+		if failures >= t.After {
+			all = append(all, t.Alerts...)
+		}
+	}
+	return all
+}
+
+// NewAlert creates an alert from its config
+// failed deliveries are pushed to the spool if not nil
+// and rate limits are enforced if limits is not nil,
+// the alert only receives the events of its route if any
+func NewAlert(cfg config.Alert, spool *alert.Spool, limits *alert.RateLimits) (alert.Alert, error) {
+	a, err := alert.GetAlert(cfg.Type, cfg.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := cfg.Timeout
+	if len(timeout) < 1 {
+		timeout = alertTimeout
+	}
+	timeoutVal, err := strconv.Atoi(timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Retries < 0 {
+		return nil, fmt.Errorf("retries cannot be negative")
+	}
+
+	var fallback alert.Alert
+	if cfg.Fallback != nil && !cfg.Fallback.Disable {
+		// only the main alert feeds the spool
+		fallback, err = NewAlert(*cfg.Fallback, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fallback %s: %v", cfg.Fallback.Type, err)
+		}
+	}
+
+	a = alert.NewReliable(a, time.Duration(timeoutVal)*time.Second, cfg.Retries, fallback, spool)
+
+	if len(cfg.RateLimit) > 0 {
+		count, window, err := alert.ParseRateLimit(cfg.RateLimit)
+		if err != nil {
+			return nil, err
+		}
+		if limits != nil {
+			a = alert.NewRateLimited(a, count, window, limits)
+		}
+	}
+
+	if len(cfg.On) > 0 || len(cfg.Checks) > 0 || len(cfg.Tags) > 0 {
+		route, err := alert.NewRoute(cfg.On, cfg.Checks, cfg.Tags)
+		if err != nil {
+			return nil, err
+		}
+		a = alert.NewRouted(a, route)
+	}
+	return a, nil
 }
 
 // ToRemote convert a config to a list of remote struct
-func ToRemote(cfg *config.Config) ([]*Remote, error) {
+func ToRemote(cfg *config.Config, spool *alert.Spool, limits *alert.RateLimits) ([]*Remote, error) {
 	// create profile map
 	profiles := make(map[string]*profileStruct)
 	isReachable, _ := check.GetCheck("reachable", nil)
 
+	sshCfg, err := loadSSHConfig(cfg.Settings.SSHConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, profile := range cfg.Profiles {
 		p := profileStruct{}
 
+		// add the alerts
+		for _, al := range profile.Alerts {
+			if al.Disable {
+				continue
+			}
+			a, err := NewAlert(al, spool, limits)
+			if err != nil {
+				return nil, fmt.Errorf("alert %s: %v", al.Type, err)
+			}
+			p.alerts = append(p.alerts, a)
+		}
+
+		// add the escalation tiers
+		for _, esc := range profile.Escalation {
+			if esc.After < 1 {
+				return nil, fmt.Errorf("profile %s: escalation \"after\" must be at least 1", profile.Name)
+			}
+			t := &Tier{
+				After: esc.After,
+			}
+			for _, al := range esc.Alerts {
+				if al.Disable {
+					continue
+				}
+				a, err := NewAlert(al, spool, limits)
+				if err != nil {
+					return nil, fmt.Errorf("alert %s: %v", al.Type, err)
+				}
+				t.Alerts = append(t.Alerts, a)
+			}
+			p.tiers = append(p.tiers, t)
+		}
+
 		// add the checks
 		for _, ch := range profile.Checks {
 			if ch.Disable {
@@ -79,18 +395,54 @@ func ToRemote(cfg *config.Config) ([]*Remote, error) {
 			if err != nil {
 				return nil, fmt.Errorf("check %s: %v", ch.Type, err)
 			}
-			p.checks = append(p.checks, checker)
-		}
-		// add the alerts
-		for _, al := range profile.Alerts {
-			if al.Disable {
-				continue
+			severity, err := alert.ParseSeverity(ch.Severity)
+			if err != nil {
+				return nil, fmt.Errorf("check %s: %v", ch.Type, err)
+			}
+			if ch.Retries < 0 {
+				return nil, fmt.Errorf("check %s: retries cannot be negative", ch.Type)
+			}
+			interval := ch.RetryInterval
+			if len(interval) < 1 {
+				interval = retryInterval
 			}
-			a, err := alert.GetAlert(al.Type, al.Options)
+			intervalVal, err := strconv.Atoi(interval)
 			if err != nil {
-				return nil, fmt.Errorf("alert %s: %v", al.Type, err)
+				return nil, fmt.Errorf("check %s: %v", ch.Type, err)
 			}
-			p.alerts = append(p.alerts, a)
+			threshold, err := parseWindow(ch.Threshold)
+			if err != nil {
+				return nil, fmt.Errorf("check %s threshold: %v", ch.Type, err)
+			}
+			flapping, err := parseWindow(ch.Flapping)
+			if err != nil {
+				return nil, fmt.Errorf("check %s flapping: %v", ch.Type, err)
+			}
+			timeout := ch.Timeout
+			if len(timeout) < 1 {
+				timeout = checkTimeout
+			}
+			timeoutVal, err := strconv.Atoi(timeout)
+			if err != nil || timeoutVal < 0 {
+				return nil, fmt.Errorf("check %s: bad timeout \"%s\"", ch.Type, timeout)
+			}
+			switch ch.Become {
+			case "", transport.BecomeSudo, transport.BecomeDoas, transport.BecomeNone:
+			default:
+				return nil, fmt.Errorf("check %s: bad become value \"%s\"", ch.Type, ch.Become)
+			}
+			hc := &HostCheck{
+				Check:         checker,
+				Severity:      severity,
+				Tiers:         p.tiers,
+				Retries:       ch.Retries,
+				RetryInterval: time.Duration(intervalVal) * time.Second,
+				Threshold:     threshold,
+				Flapping:      flapping,
+				Become:        ch.Become,
+				Timeout:       time.Duration(timeoutVal) * time.Second,
+			}
+			p.checks = append(p.checks, hc)
 		}
 		profiles[profile.Name] = &p
 	}
@@ -105,6 +457,7 @@ func ToRemote(cfg *config.Config) ([]*Remote, error) {
 			}
 
 			// loop
+			own := len(p.checks)
 			for _, other := range profile.Extend {
 				// add other profile checks and alerts
 				o, ok := profiles[other]
@@ -113,6 +466,11 @@ func ToRemote(cfg *config.Config) ([]*Remote, error) {
 				}
 				p.checks = append(p.checks, o.checks...)
 				p.alerts = append(p.alerts, o.alerts...)
+				p.tiers = append(p.tiers, o.tiers...)
+			}
+			// the profile checks escalate to the extended profiles tiers too
+			for _, hc := range p.checks[:own] {
+				hc.Tiers = p.tiers
 			}
 		}
 	}
@@ -120,16 +478,14 @@ func ToRemote(cfg *config.Config) ([]*Remote, error) {
 	// create the remotes
 	var remotes []*Remote
 	for _, host := range cfg.Hosts {
-		var thisChecks []check.Check
+		var thisChecks []*HostCheck
 		var thisAlerts []alert.Alert
+		var thisTiers []*Tier
 
 		if host.Disable {
 			continue
 		}
 
-		// add the isReachable check
-		thisChecks = append(thisChecks, isReachable)
-
 		for _, proName := range host.ProfileNames {
 			p, ok := profiles[proName]
 			if !ok {
@@ -137,16 +493,51 @@ func ToRemote(cfg *config.Config) ([]*Remote, error) {
 			}
 			thisChecks = append(thisChecks, p.checks...)
 			thisAlerts = append(thisAlerts, p.alerts...)
+			thisTiers = append(thisTiers, p.tiers...)
 		}
 
-		user := host.User
-		if len(user) < 1 {
-			user = os.Getenv("USER")
+		// add the isReachable check first
+		reachable := &HostCheck{
+			Check:    isReachable,
+			Severity: alert.SeverityCritical,
+			Tiers:    thisTiers,
+			Become:   transport.BecomeNone,
+			Timeout:  defaultCheckTimeout(),
 		}
+		thisChecks = append([]*HostCheck{reachable}, thisChecks...)
 
-		port := host.Port
-		if len(host.Port) < 1 {
-			port = "22"
+		// validate the become methods of the host and its checks
+		for _, hc := range thisChecks {
+			method := hc.Become
+			if len(method) < 1 {
+				method = host.Become
+			}
+			if len(method) < 1 || method == transport.BecomeNone {
+				continue
+			}
+			_, err := transport.NewBecome(nil, method, host.BecomeUser, host.BecomePassword)
+			if err != nil {
+				return nil, fmt.Errorf("host %s: %v", host.Name, err)
+			}
+		}
+
+		ssh := &transport.SSHOptions{
+			Host: host.Host,
+		}
+		switch host.Transport {
+		case "", transportSSH:
+			ssh, err = toSSHOptions(sshCfg, host, cfg.Settings.StateDir)
+			if err != nil {
+				return nil, fmt.Errorf("host %s: %v", host.Name, err)
+			}
+		case transportLocal:
+		case transportExec:
+			_, err = transport.NewExec(host.Exec)
+			if err != nil {
+				return nil, fmt.Errorf("host %s: %v", host.Name, err)
+			}
+		default:
+			return nil, fmt.Errorf("host %s: bad transport \"%s\"", host.Name, host.Transport)
 		}
 
 		timeout := host.Timeout
@@ -157,18 +548,39 @@ func ToRemote(cfg *config.Config) ([]*Remote, error) {
 		if err != nil {
 			return nil, err
 		}
+		var checksTimeout int
+		if len(host.ChecksTimeout) > 0 {
+			checksTimeout, err = strconv.Atoi(host.ChecksTimeout)
+			if err != nil || checksTimeout < 0 {
+				return nil, fmt.Errorf("host %s: bad checks-timeout \"%s\"", host.Name, host.ChecksTimeout)
+			}
+		}
 
 		r := &Remote{
 			Name:              host.Name,
-			Host:              host.Host,
-			Port:              port,
-			User:              user,
-			Password:          host.Password,
-			Keyfile:           host.Keyfile,
+			Host:              ssh.Host,
+			Port:              ssh.Port,
+			User:              ssh.User,
+			Password:          ssh.Password,
+			Keyfiles:          ssh.Keyfiles,
+			Certificates:      ssh.Certificates,
+			Passphrase:        ssh.Passphrase,
+			KnownHosts:        ssh.KnownHosts,
+			HostKeys:          ssh.HostKeys,
+			TOFU:              ssh.TOFU,
 			Checks:            thisChecks,
 			Alerts:            thisAlerts,
+			Tiers:             thisTiers,
 			Timeout:           timeoutVal,
+			Tags:              host.Tags,
+			Jumps:             ssh.Jumps,
 			KnownHostInsecure: host.KnownHostInsecure,
+			Become:            host.Become,
+			BecomeUser:        host.BecomeUser,
+			BecomePassword:    host.BecomePassword,
+			ChecksTimeout:     time.Duration(checksTimeout) * time.Second,
+			Transport:         host.Transport,
+			Exec:              host.Exec,
 		}
 		remotes = append(remotes, r)
 	}
@@ -176,46 +588,133 @@ func ToRemote(cfg *config.Config) ([]*Remote, error) {
 	return remotes, nil
 }
 
-// PrintRemote prints a remote
+// PrintRemote prints a remote, to stderr with the json output
 func PrintRemote(remote *Remote) {
-	fmt.Printf("Remote \"%s\" (%s):\n", remote.Name, remote.Host)
+	host := remote.Host
+	if remote.Transport == transportExec || remote.Transport == transportLocal {
+		host = remote.address()
+	}
+	fmt.Fprintf(color.Output, "Remote \"%s\" (%s):\n", remote.Name, host)
+
+	// jump hosts
+	for _, jump := range remote.Jumps {
+		fmt.Fprintf(color.Output, "  Jump: %s\n", jump.String())
+	}
 
 	// checks
-	fmt.Printf("  Checks:\n")
-	for _, check := range remote.Checks {
-		fmt.Printf("    %s: %s\n", check.GetName(), check.GetDescription())
+	fmt.Fprintf(color.Output, "  Checks:\n")
+	for _, hc := range remote.Checks {
+		check := hc.Check
+		fmt.Fprintf(color.Output, "    %s: %s (%s)\n", check.GetName(), check.GetDescription(), hc.Severity)
 		for k, v := range check.GetOptions() {
-			fmt.Printf("      - %s=%s\n", k, v)
+			fmt.Fprintf(color.Output, "      - %s=%s\n", k, v)
 		}
 	}
 
 	// alerts
-	fmt.Printf("  Alerts:\n")
+	fmt.Fprintf(color.Output, "  Alerts:\n")
 	for _, alert := range remote.Alerts {
-		fmt.Printf("    description: %s\n", alert.GetDescription())
+		fmt.Fprintf(color.Output, "    description: %s\n", alert.GetDescription())
 		for k, v := range alert.GetOptions() {
-			fmt.Printf("      - %s=%s\n", k, v)
+			fmt.Fprintf(color.Output, "      - %s=%s\n", k, v)
 		}
 	}
-}
 
-// PrintRemotes print remotes
-func PrintRemotes(remotes []*Remote) {
-	for _, remote := range remotes {
-		PrintRemote(remote)
+	// escalation
+	if len(remote.Tiers) > 0 {
+		fmt.Fprintf(color.Output, "  Escalation:\n")
+	}
+	for _, t := range remote.Tiers {
+		fmt.Fprintf(color.Output, "    after %d failure(s):\n", t.After)
+		for _, alert := range t.Alerts {
+			fmt.Fprintf(color.Output, "      description: %s\n", alert.GetDescription())
+		}
 	}
 }
 
-func notify(host string, content string, alerts []alert.Alert) {
-	for _, a := range alerts {
-		log.Debugf("notify with %s", a.GetDescription())
-		line := fmt.Sprintf("ALERT \"%s\" - %s", host, content)
-		err := a.Notify(line)
+// GetGlobalAlerts returns the alerts notified with the run summary
+func GetGlobalAlerts(cfg *config.Config, spool *alert.Spool, limits *alert.RateLimits) ([]alert.Alert, error) {
+	var cfgs []config.Alert
+	if len(cfg.Settings.GlobalAlert.Type) > 0 {
+		cfgs = append(cfgs, cfg.Settings.GlobalAlert)
+	}
+	cfgs = append(cfgs, cfg.Settings.GlobalAlerts...)
+
+	var alerts []alert.Alert
+	for _, al := range cfgs {
+		if al.Disable {
+			continue
+		}
+		a, err := NewAlert(al, spool, limits)
 		if err != nil {
-			c := fmt.Sprintf("notification error for \"%s\": ", a.GetDescription())
-			col := color.New(color.FgRed)
-			fmt.Print(c + col.Sprintln(err.Error()))
+			return nil, fmt.Errorf("global alert %s: %v", al.Type, err)
 		}
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+// Summary returns the summary of a run, nil if nothing failed
+func Summary(results []*HostResult, duration time.Duration) *alert.Event {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	var unreachable []string
+	var failing []string
+	nbHosts := 0
+	nbErr := 0
+	for _, res := range results {
+		if len(res.Unreachable) > 0 {
+			unreachable = append(unreachable, fmt.Sprintf("  - %s: %s", res.Name, res.Unreachable))
+			nbHosts++
+			nbErr++
+			continue
+		}
+		if len(res.Failures) < 1 {
+			continue
+		}
+		failing = append(failing, fmt.Sprintf("  - %s:", res.Name))
+		for _, f := range res.Failures {
+			failing = append(failing, fmt.Sprintf("    - %s: %s (value: %s, limit: %s)", f.Description, f.Error, f.Value, f.Limit))
+		}
+		nbHosts++
+		nbErr += len(res.Failures)
+	}
+	if nbHosts < 1 {
+		return nil
+	}
+
+	lines := []string{
+		fmt.Sprintf("check failed: %d/%d host(s) failed (check error: %d) in %s", nbHosts, len(results), nbErr, duration.Round(time.Millisecond)),
+	}
+	if len(unreachable) > 0 {
+		lines = append(lines, "unreachable hosts:")
+		lines = append(lines, unreachable...)
+	}
+	if len(failing) > 0 {
+		lines = append(lines, "failing hosts:")
+		lines = append(lines, failing...)
+	}
+	return alert.NewEvent("", "", "", strings.Join(lines, "\n"), alert.SeverityCritical)
+}
+
+// GetAlerts returns all the alerts of the remotes
+func GetAlerts(remotes []*Remote) []alert.Alert {
+	var alerts []alert.Alert
+	for _, r := range remotes {
+		alerts = append(alerts, r.Alerts...)
+		for _, t := range r.Tiers {
+			alerts = append(alerts, t.Alerts...)
+		}
+	}
+	return alerts
+}
+
+// PrintRemotes print remotes
+func PrintRemotes(remotes []*Remote) {
+	for _, remote := range remotes {
+		PrintRemote(remote)
 	}
 }
 
@@ -228,33 +727,110 @@ func isLocalhost(host string) bool {
 	return false
 }
 
-// CheckRemote runs the check against a remote
-func CheckRemote(remote *Remote, parallel bool, resChan chan *HostResult, doneFunc *sync.WaitGroup, out output.Output) {
+// address describes where the checks of the remote run
+func (remote *Remote) address() string {
+	switch remote.Transport {
+	case transportExec:
+		return remote.Exec
+	case transportLocal:
+		return "localhost"
+	}
+	return fmt.Sprintf("%s:%s", remote.Host, remote.Port)
+}
+
+// sshOptions returns the options to connect to the remote
+func (remote *Remote) sshOptions() *transport.SSHOptions {
+	return &transport.SSHOptions{
+		Host:         remote.Host,
+		Port:         remote.Port,
+		User:         remote.User,
+		Password:     remote.Password,
+		Keyfiles:     remote.Keyfiles,
+		Certificates: remote.Certificates,
+		Passphrase:   remote.Passphrase,
+		KnownHosts:   remote.KnownHosts,
+		HostKeys:     remote.HostKeys,
+		TOFU:         remote.TOFU,
+		Timeout:      remote.Timeout,
+		Insecure:     remote.KnownHostInsecure,
+		Jumps:        remote.Jumps,
+	}
+}
+
+// CheckRemote runs the check against a remote, its SSH
+// connection is taken from the pool if not nil
+func CheckRemote(remote *Remote, parallel bool, resChan chan *HostResult, doneFunc *sync.WaitGroup, out output.Output, notifier *Notifier, pool *transport.Pool) {
 	// create the transport
 	var trans transport.Transport
 	var err error
-	var nbChecks int
 
-	outputKey := fmt.Sprintf("%s (%s:%s)", remote.Name, remote.Host, remote.Port)
+	outputKey := fmt.Sprintf("%s (%s)", remote.Name, remote.address())
+	if named, ok := out.(output.Named); ok {
+		named.SetName(outputKey, remote.Name)
+	}
 
 	log.Debugf("connecting to %s...", remote.Name)
-	if isLocalhost(remote.Host) {
+	overSSH := false
+	if remote.Transport == transportExec {
+		trans, err = transport.NewExec(remote.Exec)
+	} else if remote.Transport == transportLocal || isLocalhost(remote.Host) {
 		trans, err = transport.NewLocal()
+	} else if pool != nil {
+		overSSH = true
+		trans, err = pool.Get(remote.sshOptions())
 	} else {
-		var keyfiles []string
-		if len(remote.Keyfile) > 0 {
-			keyfiles = append(keyfiles, remote.Keyfile)
-		}
-		trans, err = transport.NewSSH(remote.Host, remote.Port, remote.User, remote.Password, keyfiles, remote.Timeout, remote.KnownHostInsecure)
+		overSSH = true
+		trans, err = transport.NewSSH(remote.sshOptions())
 	}
 
 	if err != nil {
-		out.StackErr(outputKey, "not reachable", err.Error())
-		notify(remote.Name, fmt.Sprintf("host %s is not reachable: %v", remote.Name, err), remote.Alerts)
+		muted := 0
+		unreachable := ""
+		name := "reachable"
+		desc := "host is reachable"
+		label := "not reachable"
+		msg := fmt.Sprintf("host %s is not reachable: %v", remote.Name, err)
+
+		// a changed host key is not an outage
+		var keyErr *transport.HostKeyError
+		keyChanged := errors.As(err, &keyErr)
+		if keyChanged {
+			name = hostKeyName
+			desc = hostKeyDescription
+			label = "host key changed"
+			msg = fmt.Sprintf("host %s key changed: %v", remote.Name, keyErr)
+		}
+
+		record := notifier.record(remote, name, desc, true)
+		rule := notifier.silenced(remote, name, desc)
+		ack := notifier.acked(remote, desc)
+		if rule != nil {
+			out.StackMuted(outputKey, label, fmt.Sprintf("%s (%s)", err.Error(), rule.String()), "SILENCED")
+			muted++
+		} else if ack != nil {
+			out.StackMuted(outputKey, label, fmt.Sprintf("%s (%s)", err.Error(), ack.String()), "ACK")
+			muted++
+		} else {
+			out.StackErr(outputKey, label, err.Error())
+			event := alert.NewEvent(remote.Name, name, desc, msg, alert.SeverityCritical)
+			event.Tags = remote.Tags
+			event.Failures = record.Failures
+			alerts := escalate(remote.Alerts, remote.Tiers, record.Failures)
+			if keyChanged {
+				notifier.notify(event, alerts)
+			} else {
+				notifier.hostDown(event, alerts)
+			}
+			notifier.notified(remote, desc, record.Failures)
+			unreachable = err.Error()
+		}
 		out.Flush(outputKey)
 		resChan <- &HostResult{
-			NbCheckTotal: nbChecks,
+			Name:         remote.Name,
+			Unreachable:  unreachable,
+			NbCheckTotal: 0,
 			NbCheckError: 1,
+			NbCheckMuted: muted,
 		}
 		doneFunc.Done()
 		return
@@ -263,25 +839,38 @@ func CheckRemote(remote *Remote, parallel bool, resChan chan *HostResult, doneFu
 	// defer closing the sessions
 	defer trans.Close()
 
+	if overSSH {
+		notifier.trusted(remote)
+	}
+
 	// create the result channel
-	ch := make(chan *check.Result, len(remote.Checks))
+	ch := make(chan *hostResult, len(remote.Checks))
 	// create the jobs channel
 	maxJob := 1
 	if parallel {
 		maxJob = maxJobs
 	}
-	jobs := make(chan check.Check, maxJob)
+	jobs := make(chan *HostCheck, maxJob)
 	// create the end of process channel
-	endChan := make(chan int, 1)
+	endChan := make(chan *HostResult, 1)
 
 	// checker worker
 	// reads checks from jobs channel
 	// and push results to result channel
 	go func() {
-		for check := range jobs {
-			log.Debugf("running check %s", check.GetDescription())
-			res := check.Run(trans)
-			ch <- res
+		ctx := context.Background()
+		if remote.ChecksTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, remote.ChecksTimeout)
+			defer cancel()
+		}
+		becomes := make(map[string]transport.Transport)
+		for hc := range jobs {
+			log.Debugf("running check %s", hc.Check.GetDescription())
+			ch <- &hostResult{
+				res: hc.run(ctx, remote.transportFor(trans, hc, becomes)),
+				hc:  hc,
+			}
 		}
 		close(ch)
 	}()
@@ -289,37 +878,83 @@ func CheckRemote(remote *Remote, parallel bool, resChan chan *HostResult, doneFu
 	// process results worker
 	// handles the results and construct output
 	go func() {
-		errCnt := 0
-		for res := range ch {
-			if res.Error != nil {
-				// alert notification
-				errStr := fmt.Sprintf("%s: %s", res.Description, res.Error)
-				notify(remote.Name, errStr, remote.Alerts)
-				// output
-				out.StackErr(outputKey, res.Description, res.Error.Error())
-				errCnt++
-			} else {
+		hostRes := &HostResult{
+			Name: remote.Name,
+		}
+		for hr := range ch {
+			res := hr.res
+			hostRes.NbCheckTotal++
+			record := notifier.record(remote, res.Name, res.Description, res.Error != nil)
+			if res.Error == nil {
 				out.StackOk(outputKey, res.Description, res.Value)
+				if record.Notified > 0 {
+					notifier.recovered(remote, hr.hc, res, record)
+				}
+				continue
+			}
+
+			hostRes.NbCheckError++
+
+			// reported but not notified
+			mute := func(label string, reason string) {
+				out.StackMuted(outputKey, res.Description, fmt.Sprintf("%s (%s)", res.Error.Error(), reason), label)
+				hostRes.NbCheckMuted++
+			}
+			rule := notifier.silenced(remote, res.Name, res.Description)
+			if rule != nil {
+				mute("SILENCED", rule.String())
+				continue
+			}
+			ack := notifier.acked(remote, res.Description)
+			if ack != nil {
+				mute("ACK", ack.String())
+				continue
+			}
+			reason := hr.hc.flapping(record)
+			if len(reason) > 0 {
+				mute("FLAPPING", reason)
+				continue
+			}
+			reason = hr.hc.pending(record)
+			if len(reason) > 0 {
+				mute("PENDING", reason)
+				continue
+			}
+
+			// alert notification
+			errStr := fmt.Sprintf("%s: %s", res.Description, res.Error)
+			event := alert.NewEvent(remote.Name, res.Name, res.Description, errStr, hr.hc.Severity)
+			event.Tags = remote.Tags
+			event.Failures = record.Failures
+			notifier.notify(event, escalate(remote.Alerts, hr.hc.Tiers, record.Failures))
+			notifier.notified(remote, res.Description, record.Failures)
+			hostRes.Failures = append(hostRes.Failures, &Failure{
+				Description: res.Description,
+				Value:       res.Value,
+				Limit:       res.Limit,
+				Error:       res.Error.Error(),
+			})
+			// output
+			if res.Timeout {
+				out.StackTimeout(outputKey, res.Description, res.Error.Error())
+			} else {
+				out.StackErr(outputKey, res.Description, res.Error.Error())
 			}
 		}
-		endChan <- errCnt
+		endChan <- hostRes
 	}()
 
 	// send the jobs
 	for _, c := range remote.Checks {
-		nbChecks++
 		jobs <- c
 	}
 	close(jobs)
 
 	// wait for result processing to end
-	errCnt := <-endChan
+	hostRes := <-endChan
 
 	// print output
 	out.Flush(outputKey)
-	resChan <- &HostResult{
-		NbCheckTotal: nbChecks,
-		NbCheckError: errCnt,
-	}
+	resChan <- hostRes
 	doneFunc.Done()
 }
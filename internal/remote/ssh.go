@@ -0,0 +1,192 @@
+// Copyright (c) 2021 deadc0de6
+
+package remote
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/deadc0de6/checkah/internal/config"
+	"github.com/deadc0de6/checkah/internal/state"
+	"github.com/deadc0de6/checkah/internal/transport"
+)
+
+const (
+	sshConfigNone  = "none"
+	knownHostsFile = "known_hosts"
+)
+
+// loadSSHConfig loads the OpenSSH client config,
+// only the default one may be missing
+func loadSSHConfig(path string) (*transport.SSHConfig, error) {
+	if path == sshConfigNone {
+		return nil, nil
+	}
+	if len(path) < 1 {
+		return transport.LoadSSHConfig(filepath.Join(os.Getenv("HOME"), ".ssh", "config"))
+	}
+	path = transport.ExpandPath(path, "", "")
+	_, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return transport.LoadSSHConfig(path)
+}
+
+// withSSHConfig fills the empty options with the OpenSSH client config
+func withSSHConfig(sshCfg *transport.SSHConfig, o *transport.SSHOptions) {
+	alias := o.Host
+	hostname := sshCfg.Get(alias, "HostName")
+	if len(hostname) > 0 {
+		o.Host = strings.ReplaceAll(hostname, "%h", alias)
+	}
+	if len(o.Port) < 1 {
+		o.Port = sshCfg.Get(alias, "Port")
+	}
+	if len(o.User) < 1 {
+		o.User = sshCfg.Get(alias, "User")
+	}
+	if len(o.Keyfiles) < 1 {
+		for _, id := range sshCfg.GetAll(alias, "IdentityFile") {
+			o.Keyfiles = append(o.Keyfiles, transport.ExpandPath(id, o.Host, o.User))
+		}
+	}
+	if len(o.Certificates) < 1 {
+		for _, cert := range sshCfg.GetAll(alias, "CertificateFile") {
+			o.Certificates = append(o.Certificates, transport.ExpandPath(cert, o.Host, o.User))
+		}
+	}
+	if len(o.KnownHosts) < 1 {
+		for _, value := range sshCfg.GetAll(alias, "UserKnownHostsFile") {
+			for _, path := range strings.Fields(value) {
+				if path == sshConfigNone {
+					continue
+				}
+				o.KnownHosts = append(o.KnownHosts, transport.ExpandPath(path, o.Host, o.User))
+			}
+		}
+	}
+
+	// defaults
+	if len(o.Port) < 1 {
+		o.Port = "22"
+	}
+	if len(o.User) < 1 {
+		o.User = os.Getenv("USER")
+	}
+}
+
+// toSSHOptions returns the SSH options of a host, its values
+// come first, then the OpenSSH client config ones
+func toSSHOptions(sshCfg *transport.SSHConfig, host config.Host, stateDir string) (*transport.SSHOptions, error) {
+	o := &transport.SSHOptions{
+		Host:     host.Host,
+		Port:     host.Port,
+		User:     host.User,
+		Password: host.Password,
+	}
+	if len(host.Keyfile) > 0 {
+		o.Keyfiles = append(o.Keyfiles, host.Keyfile)
+	}
+	if len(host.Certificate) > 0 {
+		o.Certificates = append(o.Certificates, transport.ExpandPath(host.Certificate, host.Host, host.User))
+	}
+	pass, err := toPassphrase(host)
+	if err != nil {
+		return nil, err
+	}
+	o.Passphrase = pass
+	if len(host.KnownHosts) > 0 {
+		o.KnownHosts = append(o.KnownHosts, transport.ExpandPath(host.KnownHosts, host.Host, host.User))
+	}
+	for _, fp := range strings.Split(host.HostKey, ",") {
+		fp = strings.TrimSpace(fp)
+		if len(fp) < 1 {
+			continue
+		}
+		if !strings.HasPrefix(fp, "SHA256:") {
+			return nil, fmt.Errorf("bad host-key \"%s\", expecting SHA256:<fingerprint>", fp)
+		}
+		o.HostKeys = append(o.HostKeys, fp)
+	}
+	if host.TOFU {
+		o.TOFU = state.Path(stateDir, knownHostsFile)
+	}
+	alias := o.Host
+	withSSHConfig(sshCfg, o)
+
+	proxyJump := host.ProxyJump
+	if len(proxyJump) < 1 && len(host.Jump) < 1 {
+		proxyJump = sshCfg.Get(alias, "ProxyJump")
+	}
+	if proxyJump == sshConfigNone {
+		proxyJump = ""
+	}
+	jumps, err := toJumps(sshCfg, proxyJump, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, jump := range jumps {
+		jump.Passphrase = pass
+	}
+	o.Jumps = jumps
+	return o, nil
+}
+
+// toPassphrase returns the passphrase source of a host, nil if none
+func toPassphrase(host config.Host) (*transport.Passphrase, error) {
+	n := 0
+	for _, v := range []string{host.PassphraseEnv, host.PassphraseFile, host.PassphraseCommand} {
+		if len(v) > 0 {
+			n++
+		}
+	}
+	if n < 1 {
+		return nil, nil
+	}
+	if n > 1 {
+		return nil, fmt.Errorf("only one of \"passphrase-env\", \"passphrase-file\" and \"passphrase-command\" can be set")
+	}
+	p := &transport.Passphrase{
+		Env:     host.PassphraseEnv,
+		File:    host.PassphraseFile,
+		Command: host.PassphraseCommand,
+	}
+	return p, nil
+}
+
+// toJumps returns the jump hosts of a host, the "proxy-jump"
+// ones first, using the host keyfile and certificate if they have none
+func toJumps(sshCfg *transport.SSHConfig, proxyJump string, host config.Host) ([]*transport.SSHOptions, error) {
+	jumps, err := transport.ParseProxyJump(proxyJump)
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range host.Jump {
+		if len(j.Host) < 1 {
+			return nil, fmt.Errorf("jump host cannot be empty")
+		}
+		jump := &transport.SSHOptions{
+			Host:     j.Host,
+			Port:     j.Port,
+			User:     j.User,
+			Password: j.Password,
+		}
+		if len(j.Keyfile) > 0 {
+			jump.Keyfiles = append(jump.Keyfiles, j.Keyfile)
+		}
+		jumps = append(jumps, jump)
+	}
+	for _, jump := range jumps {
+		if len(jump.Keyfiles) < 1 && len(host.Keyfile) > 0 {
+			jump.Keyfiles = append(jump.Keyfiles, host.Keyfile)
+			if len(host.Certificate) > 0 {
+				jump.Certificates = append(jump.Certificates, transport.ExpandPath(host.Certificate, host.Host, host.User))
+			}
+		}
+		withSSHConfig(sshCfg, jump)
+	}
+	return jumps, nil
+}
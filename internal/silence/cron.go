@@ -0,0 +1,133 @@
+// Copyright (c) 2021 deadc0de6
+
+package silence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// a cron field as a set of allowed values
+type cronField struct {
+	values map[int]bool
+	any    bool
+}
+
+// cron a "minute hour day-of-month month day-of-week" expression
+type cron struct {
+	minute cronField
+	hour   cronField
+	dom    cronField
+	month  cronField
+	dow    cronField
+}
+
+func parseCronRange(part string, lo int, hi int) (int, int, error) {
+	if part == "*" {
+		return lo, hi, nil
+	}
+	fields := strings.SplitN(part, "-", 2)
+	start, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	end := start
+	if len(fields) > 1 {
+		end, err = strconv.Atoi(fields[1])
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if start < lo || end > hi || start > end {
+		return 0, 0, fmt.Errorf("\"%s\" out of range %d-%d", part, lo, hi)
+	}
+	return start, end, nil
+}
+
+func parseCronField(field string, lo int, hi int) (cronField, error) {
+	f := cronField{
+		values: make(map[int]bool),
+		any:    field == "*",
+	}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		fields := strings.SplitN(part, "/", 2)
+		if len(fields) > 1 {
+			var err error
+			step, err = strconv.Atoi(fields[1])
+			if err != nil || step < 1 {
+				return f, fmt.Errorf("bad step in \"%s\"", part)
+			}
+		}
+		start, end, err := parseCronRange(fields[0], lo, hi)
+		if err != nil {
+			return f, err
+		}
+		for i := start; i <= end; i += step {
+			f.values[i] = true
+		}
+	}
+	return f, nil
+}
+
+func parseCron(expr string) (*cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("bad cron expression \"%s\": expecting 5 fields", expr)
+	}
+
+	var err error
+	c := &cron{}
+	c.minute, err = parseCronField(fields[0], 0, 59)
+	if err != nil {
+		return nil, fmt.Errorf("bad cron minute: %v", err)
+	}
+	c.hour, err = parseCronField(fields[1], 0, 23)
+	if err != nil {
+		return nil, fmt.Errorf("bad cron hour: %v", err)
+	}
+	c.dom, err = parseCronField(fields[2], 1, 31)
+	if err != nil {
+		return nil, fmt.Errorf("bad cron day of month: %v", err)
+	}
+	c.month, err = parseCronField(fields[3], 1, 12)
+	if err != nil {
+		return nil, fmt.Errorf("bad cron month: %v", err)
+	}
+	c.dow, err = parseCronField(fields[4], 0, 7)
+	if err != nil {
+		return nil, fmt.Errorf("bad cron day of week: %v", err)
+	}
+	// sunday is both 0 and 7
+	if c.dow.values[7] {
+		c.dow.values[0] = true
+	}
+	return c, nil
+}
+
+// match returns true if the expression fires at t
+func (c *cron) match(t time.Time) bool {
+	if !c.minute.values[t.Minute()] || !c.hour.values[t.Hour()] || !c.month.values[int(t.Month())] {
+		return false
+	}
+	dom := c.dom.values[t.Day()]
+	dow := c.dow.values[int(t.Weekday())]
+	// like cron, if both days are restricted, any of them matches
+	if !c.dom.any && !c.dow.any {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// active returns true if the expression fired within duration before now
+func (c *cron) active(now time.Time, duration time.Duration) bool {
+	start := now.Add(-duration)
+	for t := now.Truncate(time.Minute); t.After(start); t = t.Add(-time.Minute) {
+		if c.match(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2021 deadc0de6
+
+package silence
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr string
+		ok   bool
+	}{
+		{"* * * * *", true},
+		{"0 2 * * 0", true},
+		{"*/15 0-6 1,15 1-12/2 1-5", true},
+		{"0 0 * * 7", true},
+		{"0 0 31 12 *", true},
+		{"* * * *", false},
+		{"* * * * * *", false},
+		{"60 * * * *", false},
+		{"* 24 * * *", false},
+		{"* * 0 * *", false},
+		{"* * * 13 *", false},
+		{"* * * * 8", false},
+		{"5-1 * * * *", false},
+		{"*/0 * * * *", false},
+		{"*/x * * * *", false},
+		{"a * * * *", false},
+		{"1-b * * * *", false},
+	}
+	for _, tt := range tests {
+		_, err := parseCron(tt.expr)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseCron(%q) error = %v, want ok %t", tt.expr, err, tt.ok)
+		}
+	}
+}
+
+func TestCronMatch(t *testing.T) {
+	// a monday
+	monday := time.Date(2024, time.January, 15, 2, 30, 0, 0, time.UTC)
+	sunday := time.Date(2024, time.January, 14, 2, 30, 0, 0, time.UTC)
+	first := time.Date(2024, time.February, 1, 2, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		t    time.Time
+		want bool
+	}{
+		{"* * * * *", monday, true},
+		{"30 2 * * *", monday, true},
+		{"31 2 * * *", monday, false},
+		{"30 3 * * *", monday, false},
+		{"*/15 * * * *", monday, true},
+		{"*/20 * * * *", monday, false},
+		{"0-29 * * * *", monday, false},
+		{"25-35 * * * *", monday, true},
+		{"30 2 * * 1", monday, true},
+		{"30 2 * * 1-5", sunday, false},
+		// sunday is both 0 and 7
+		{"30 2 * * 0", sunday, true},
+		{"30 2 * * 7", sunday, true},
+		{"30 2 * 1 *", first, false},
+		{"30 2 * 2 *", first, true},
+		// both days restricted, any of them matches
+		{"30 2 1 * 1", monday, true},
+		{"30 2 1 * 1", first, true},
+		{"30 2 1 * 0", monday, false},
+		// one day restricted, both must match
+		{"30 2 1 * *", monday, false},
+		{"30 2 * * 4", first, true},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.expr, err)
+		}
+		got := c.match(tt.t)
+		if got != tt.want {
+			t.Errorf("%q match %s = %t, want %t", tt.expr, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestCronActive(t *testing.T) {
+	tests := []struct {
+		expr     string
+		now      time.Time
+		duration time.Duration
+		want     bool
+	}{
+		{"0 2 * * *", time.Date(2024, time.January, 15, 2, 0, 0, 0, time.UTC), time.Hour, true},
+		{"0 2 * * *", time.Date(2024, time.January, 15, 2, 59, 59, 0, time.UTC), time.Hour, true},
+		{"0 2 * * *", time.Date(2024, time.January, 15, 3, 0, 0, 0, time.UTC), time.Hour, false},
+		{"0 2 * * *", time.Date(2024, time.January, 15, 1, 59, 0, 0, time.UTC), time.Hour, false},
+		// fired the day before and still running
+		{"0 22 * * *", time.Date(2024, time.January, 16, 1, 0, 0, 0, time.UTC), 4 * time.Hour, true},
+		{"0 22 * * *", time.Date(2024, time.January, 16, 2, 0, 0, 0, time.UTC), 4 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.expr, err)
+		}
+		got := c.active(tt.now, tt.duration)
+		if got != tt.want {
+			t.Errorf("%q active at %s for %s = %t, want %t", tt.expr, tt.now, tt.duration, got, tt.want)
+		}
+	}
+}
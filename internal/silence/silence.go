@@ -0,0 +1,241 @@
+// Copyright (c) 2021 deadc0de6
+
+package silence
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/deadc0de6/checkah/internal/config"
+	"github.com/deadc0de6/checkah/internal/state"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	timeFormats = []string{
+		time.RFC3339,
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+	}
+)
+
+// Rule a silence rule, empty hosts, tags
+// or checks match everything
+type Rule struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name,omitempty"`
+	Hosts    []string  `json:"hosts,omitempty"`
+	Tags     []string  `json:"tags,omitempty"`
+	Checks   []string  `json:"checks,omitempty"`
+	Start    time.Time `json:"start,omitempty"`
+	End      time.Time `json:"end,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	cron     *cron
+	duration time.Duration
+}
+
+// Active returns true if the rule is in effect at now
+func (r *Rule) Active(now time.Time) bool {
+	if r.cron != nil {
+		return r.cron.active(now, r.duration)
+	}
+	if !r.Start.IsZero() && now.Before(r.Start) {
+		return false
+	}
+	if !r.End.IsZero() && !now.Before(r.End) {
+		return false
+	}
+	return true
+}
+
+// patterns are globs matched against any of the values
+func matchAny(patterns []string, values ...string) bool {
+	if len(patterns) < 1 {
+		return true
+	}
+	for _, p := range patterns {
+		for _, v := range values {
+			ok, _ := path.Match(p, v)
+			if ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Match returns true if the rule applies to this check on this host,
+// the check is matched by its name or its description
+func (r *Rule) Match(host string, tags []string, check string, description string) bool {
+	if !matchAny(r.Hosts, host) {
+		return false
+	}
+	if len(r.Tags) > 0 && !matchAny(r.Tags, tags...) {
+		return false
+	}
+	return matchAny(r.Checks, check, description)
+}
+
+// String returns a description of the rule
+func (r *Rule) String() string {
+	what := fmt.Sprintf("silence %s", r.ID)
+	if len(r.Name) > 0 {
+		what = fmt.Sprintf("maintenance \"%s\"", r.Name)
+	}
+	if len(r.Reason) > 0 {
+		return fmt.Sprintf("%s (%s)", what, r.Reason)
+	}
+	return what
+}
+
+// Silences the silence rules active during a run
+type Silences struct {
+	rules []*Rule
+}
+
+// Match returns the first rule silencing this check, nil if none
+func (s *Silences) Match(host string, tags []string, check string, description string) *Rule {
+	if s == nil {
+		return nil
+	}
+	for _, r := range s.rules {
+		if r.Match(host, tags, check, description) {
+			return r
+		}
+	}
+	return nil
+}
+
+// New returns the silences for the rules active at now
+func New(rules []*Rule, now time.Time) *Silences {
+	s := &Silences{}
+	for _, r := range rules {
+		if r.Active(now) {
+			s.rules = append(s.rules, r)
+		}
+	}
+	return s
+}
+
+// ParseTime parses an absolute time in local time
+func ParseTime(value string) (time.Time, error) {
+	for _, f := range timeFormats {
+		t, err := time.ParseInLocation(f, value, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("bad time \"%s\"", value)
+}
+
+// FromMaintenance converts the maintenance windows to rules
+func FromMaintenance(windows []config.Maintenance) ([]*Rule, error) {
+	var rules []*Rule
+	for i, w := range windows {
+		name := w.Name
+		if len(name) < 1 {
+			name = fmt.Sprintf("#%d", i)
+		}
+
+		r := &Rule{
+			ID:     name,
+			Name:   name,
+			Hosts:  w.Hosts,
+			Tags:   w.Tags,
+			Checks: w.Checks,
+			Reason: w.Reason,
+		}
+
+		if len(w.Cron) > 0 {
+			c, err := parseCron(w.Cron)
+			if err != nil {
+				return nil, fmt.Errorf("maintenance %s: %v", name, err)
+			}
+			if len(w.Duration) < 1 {
+				return nil, fmt.Errorf("maintenance %s: \"duration\" required with \"cron\"", name)
+			}
+			d, err := time.ParseDuration(w.Duration)
+			if err != nil {
+				return nil, fmt.Errorf("maintenance %s: %v", name, err)
+			}
+			r.cron = c
+			r.duration = d
+			rules = append(rules, r)
+			continue
+		}
+
+		if len(w.Start) < 1 || len(w.End) < 1 {
+			return nil, fmt.Errorf("maintenance %s: either \"cron\" or \"start\" and \"end\" required", name)
+		}
+		var err error
+		r.Start, err = ParseTime(w.Start)
+		if err != nil {
+			return nil, fmt.Errorf("maintenance %s: %v", name, err)
+		}
+		r.End, err = ParseTime(w.End)
+		if err != nil {
+			return nil, fmt.Errorf("maintenance %s: %v", name, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Store the ad-hoc silences kept in the state directory
+type Store struct {
+	path  string
+	Rules []*Rule
+}
+
+// Add adds a new silence and returns its id
+func (s *Store) Add(r *Rule) string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	r.ID = hex.EncodeToString(b)
+	s.Rules = append(s.Rules, r)
+	return r.ID
+}
+
+// Remove removes a silence by id
+func (s *Store) Remove(id string) error {
+	for i, r := range s.Rules {
+		if r.ID == id {
+			s.Rules = append(s.Rules[:i], s.Rules[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no such silence: %s", id)
+}
+
+// Save writes the silences that are not expired
+func (s *Store) Save() error {
+	now := time.Now()
+	rules := []*Rule{}
+	for _, r := range s.Rules {
+		if !r.End.IsZero() && !now.Before(r.End) {
+			continue
+		}
+		rules = append(rules, r)
+	}
+	s.Rules = rules
+	return state.Save(s.path, s.Rules)
+}
+
+// Load loads the silences from the state directory
+func Load(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+	}
+	err := state.Load(path, &s.Rules)
+	if state.IsCorrupt(err) {
+		log.Warnf("ignoring corrupt silences %s: %v", path, err)
+		s.Rules = nil
+	} else if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 deadc0de6
+
+package silence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRuleMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		rule        Rule
+		host        string
+		tags        []string
+		check       string
+		description string
+		want        bool
+	}{
+		{"empty matches everything", Rule{}, "web1", nil, "disk", "disk usage /", true},
+		{"host", Rule{Hosts: []string{"web1"}}, "web1", nil, "disk", "disk usage /", true},
+		{"other host", Rule{Hosts: []string{"web1"}}, "web2", nil, "disk", "disk usage /", false},
+		{"host glob", Rule{Hosts: []string{"web*"}}, "web2", nil, "disk", "disk usage /", true},
+		{"any host", Rule{Hosts: []string{"db*", "web2"}}, "web2", nil, "disk", "disk usage /", true},
+		{"tag", Rule{Tags: []string{"prod"}}, "web1", []string{"eu", "prod"}, "disk", "disk usage /", true},
+		{"tag glob", Rule{Tags: []string{"pr*"}}, "web1", []string{"eu", "prod"}, "disk", "disk usage /", true},
+		{"other tag", Rule{Tags: []string{"dev"}}, "web1", []string{"eu", "prod"}, "disk", "disk usage /", false},
+		{"no tags", Rule{Tags: []string{"prod"}}, "web1", nil, "disk", "disk usage /", false},
+		{"check name", Rule{Checks: []string{"disk"}}, "web1", nil, "disk", "disk usage /", true},
+		{"check description", Rule{Checks: []string{"disk usage /"}}, "web1", nil, "disk", "disk usage /", true},
+		{"check glob", Rule{Checks: []string{"memory *"}}, "web1", nil, "memory", "memory usage", true},
+		{"other check", Rule{Checks: []string{"memory"}}, "web1", nil, "disk", "disk usage /", false},
+		{
+			"all selectors",
+			Rule{Hosts: []string{"web*"}, Tags: []string{"prod"}, Checks: []string{"disk"}},
+			"web1", []string{"prod"}, "disk", "disk usage /", true,
+		},
+		{
+			"one selector differs",
+			Rule{Hosts: []string{"web*"}, Tags: []string{"prod"}, Checks: []string{"disk"}},
+			"db1", []string{"prod"}, "disk", "disk usage /", false,
+		},
+		{"bad pattern", Rule{Hosts: []string{"web["}}, "web[", nil, "disk", "disk usage /", false},
+	}
+	for _, tt := range tests {
+		got := tt.rule.Match(tt.host, tt.tags, tt.check, tt.description)
+		if got != tt.want {
+			t.Errorf("%s: Match(%q, %v, %q, %q) = %t, want %t",
+				tt.name, tt.host, tt.tags, tt.check, tt.description, got, tt.want)
+		}
+	}
+}
+
+func TestRuleActive(t *testing.T) {
+	start := time.Date(2024, time.January, 15, 2, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	tests := []struct {
+		name string
+		rule Rule
+		now  time.Time
+		want bool
+	}{
+		{"no bounds", Rule{}, start, true},
+		{"before start", Rule{Start: start, End: end}, start.Add(-time.Second), false},
+		{"at start", Rule{Start: start, End: end}, start, true},
+		{"within", Rule{Start: start, End: end}, start.Add(30 * time.Minute), true},
+		{"at end", Rule{Start: start, End: end}, end, false},
+		{"no end", Rule{Start: start}, end.Add(time.Hour), true},
+		{"no start", Rule{End: end}, start.Add(-time.Hour), true},
+	}
+	for _, tt := range tests {
+		got := tt.rule.Active(tt.now)
+		if got != tt.want {
+			t.Errorf("%s: Active(%s) = %t, want %t", tt.name, tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "silences.json")
+	err := os.WriteFile(path, []byte(`[{"id":`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load of a corrupt file: %v", err)
+	}
+	if len(s.Rules) > 0 {
+		t.Errorf("rules = %v, want none", s.Rules)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2021 deadc0de6
+
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Dir returns the state directory
+func Dir(dir string) string {
+	if len(dir) > 0 {
+		if strings.HasPrefix(dir, "~/") {
+			// handle tild
+			dir = filepath.Join(os.Getenv("HOME"), dir[2:])
+		}
+		return dir
+	}
+
+	base := os.Getenv("XDG_STATE_HOME")
+	if len(base) < 1 {
+		base = filepath.Join(os.Getenv("HOME"), ".local", "state")
+	}
+	return filepath.Join(base, "checkah")
+}
+
+// Path returns the path of a state file
+func Path(dir string, name string) string {
+	return filepath.Join(Dir(dir), name)
+}
+
+// Load reads a state file into v, a missing file is not an error
+func Load(path string, v interface{}) error {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
+
+// IsCorrupt returns true if the error is a state file that cannot be decoded
+func IsCorrupt(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
+
+// Lock locks a state file against the other processes,
+// with a lock file next to it, until unlock is called
+func Lock(path string) (func(), error) {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	// closing releases the lock
+	unlock := func() {
+		f.Close()
+	}
+	return unlock, nil
+}
+
+// Save atomically writes v to a state file
+func Save(path string, v interface{}) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(content)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Sync()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
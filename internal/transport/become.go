@@ -0,0 +1,173 @@
+// Copyright (c) 2021 deadc0de6
+
+package transport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// privilege escalation methods
+const (
+	BecomeSudo = "sudo"
+	BecomeDoas = "doas"
+	BecomeNone = "none"
+	// sudo prompt, to tell it from the command output
+	becomePrompt = "[checkah-become-password]"
+)
+
+var (
+	becomeNoPassword = []string{
+		"a password is required",
+		"a terminal is required",
+		"a tty is required",
+		"Authentication required",
+		"Authorization required",
+	}
+	becomeBadPassword = []string{
+		"incorrect password",
+		"Sorry, try again",
+		"Authentication failed",
+	}
+	becomeNotPermitted = []string{
+		"is not in the sudoers file",
+		"is not allowed to execute",
+		"may not run sudo",
+		"Operation not permitted",
+	}
+)
+
+// Become a transport running the commands as another user with sudo or doas
+type Become struct {
+	Transport
+	method   string
+	user     string
+	password string
+}
+
+// quote single quotes a string for the remote shell
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// wrap returns the command run through sudo or doas,
+// sudo reads the password from stdin only with prompt
+func (b *Become) wrap(cmd string, prompt bool) string {
+	var args []string
+	if b.method == BecomeDoas {
+		args = append(args, "doas", "-n")
+		if len(b.user) > 0 {
+			args = append(args, "-u", quote(b.user))
+		}
+	} else {
+		args = append(args, "sudo")
+		if prompt {
+			args = append(args, "-S", "-p", quote(becomePrompt))
+		} else {
+			args = append(args, "-n")
+		}
+		if len(b.user) > 0 {
+			args = append(args, "-u", quote(b.user))
+		}
+		args = append(args, "--")
+	}
+	args = append(args, "sh", "-c", quote(cmd))
+	return strings.Join(args, " ")
+}
+
+// lines returns the lines of stderr written by sudo or doas
+func (b *Become) lines(stderr string) []string {
+	var lines []string
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.ReplaceAll(line, becomePrompt, "")
+		if strings.HasPrefix(line, b.method+":") || strings.HasPrefix(line, "Sorry") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func containsAny(lines []string, patterns []string) bool {
+	for _, line := range lines {
+		for _, p := range patterns {
+			if strings.Contains(line, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// failure returns an error if the command failed because of
+// sudo or doas, nil if the command itself failed
+func (b *Become) failure(stderr string) error {
+	lines := b.lines(stderr)
+	prompts := strings.Count(stderr, becomePrompt)
+	switch {
+	case prompts > 1 || containsAny(lines, becomeBadPassword):
+		return fmt.Errorf("%s: incorrect password", b.method)
+	case containsAny(lines, becomeNotPermitted):
+		return fmt.Errorf("%s: not permitted: %s", b.method, strings.Join(lines, " "))
+	case containsAny(lines, becomeNoPassword):
+		if b.method == BecomeDoas {
+			return fmt.Errorf("%s: a password is required, only passwordless (nopass/persist) rules are supported", b.method)
+		}
+		return fmt.Errorf("%s: a password is required and none is configured", b.method)
+	case strings.Contains(stderr, b.method+": command not found") || strings.Contains(stderr, b.method+": not found"):
+		return fmt.Errorf("%s: not installed", b.method)
+	}
+	return nil
+}
+
+// Execute executes a command with sudo or doas
+func (b *Become) Execute(ctx context.Context, cmd string) (*Result, error) {
+	return b.ExecuteWithInput(ctx, cmd, "")
+}
+
+// ExecuteWithInput executes a command with sudo or doas, sudo is
+// run non-interactively first and only if it requires a password,
+// again with the password written to stdin before the input, for
+// it not to reach the command when sudo does not prompt
+func (b *Become) ExecuteWithInput(ctx context.Context, cmd string, input string) (*Result, error) {
+	log.Debugf("%s run: \"%s\"", b.method, cmd)
+	res, err := b.Transport.ExecuteWithInput(ctx, b.wrap(cmd, false), input)
+	if err != nil && !res.TransportError && b.method == BecomeSudo && len(b.password) > 0 &&
+		containsAny(b.lines(res.Stderr), becomeNoPassword) {
+		// sudo did not run the command
+		log.Debugf("%s requires a password for \"%s\"", b.method, cmd)
+		res, err = b.Transport.ExecuteWithInput(ctx, b.wrap(cmd, true), b.password+"\n"+input)
+	}
+	if err != nil && !res.TransportError {
+		ferr := b.failure(res.Stderr)
+		if ferr != nil {
+			log.Debugf("%s failed for \"%s\": %v (%s)", b.method, cmd, ferr, res.Stderr)
+			err = ferr
+		}
+	}
+	res.Stderr = strings.ReplaceAll(res.Stderr, becomePrompt, "")
+	return res, err
+}
+
+// NewBecome wraps a transport to run its commands
+// as user (root if empty) with sudo or doas
+func NewBecome(t Transport, method string, user string, password string) (*Become, error) {
+	switch method {
+	case BecomeSudo:
+	case BecomeDoas:
+		if len(password) > 0 {
+			return nil, fmt.Errorf("doas cannot read a password without a terminal")
+		}
+	default:
+		return nil, fmt.Errorf("bad become method \"%s\"", method)
+	}
+	b := &Become{
+		Transport: t,
+		method:    method,
+		user:      user,
+		password:  password,
+	}
+	return b, nil
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2021 deadc0de6
+
+package transport
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// fakeTransport returns the results in order
+// and records the commands with their input
+type fakeTransport struct {
+	Local
+	results []*Result
+	cmds    []string
+	inputs  []string
+}
+
+func (f *fakeTransport) ExecuteWithInput(_ context.Context, cmd string, input string) (*Result, error) {
+	f.cmds = append(f.cmds, cmd)
+	f.inputs = append(f.inputs, input)
+	res := f.results[0]
+	f.results = f.results[1:]
+	if res.ExitCode != 0 {
+		return res, &ExitError{Code: res.ExitCode}
+	}
+	return res, nil
+}
+
+func TestQuote(t *testing.T) {
+	tests := []string{
+		"",
+		"plain",
+		"with space",
+		"it's",
+		"''",
+		`"double" $HOME $(id) ` + "`id`",
+		`back\slash`,
+		"new\nline",
+	}
+	l := &Local{}
+	for _, s := range tests {
+		res, err := l.Execute(t.Context(), "printf %s "+quote(s))
+		if err != nil {
+			t.Errorf("quote(%q): %v", s, err)
+			continue
+		}
+		if res.Stdout != s {
+			t.Errorf("quote(%q) read back as %q", s, res.Stdout)
+		}
+	}
+}
+
+func TestBecomeWrap(t *testing.T) {
+	tests := []struct {
+		method string
+		user   string
+		prompt bool
+		want   string
+	}{
+		{BecomeSudo, "", false, `sudo -n -- sh -c 'id'`},
+		{BecomeSudo, "postgres", false, `sudo -n -u 'postgres' -- sh -c 'id'`},
+		{BecomeSudo, "", true, `sudo -S -p '[checkah-become-password]' -- sh -c 'id'`},
+		{BecomeSudo, "postgres", true, `sudo -S -p '[checkah-become-password]' -u 'postgres' -- sh -c 'id'`},
+		{BecomeDoas, "", false, `doas -n sh -c 'id'`},
+		{BecomeDoas, "postgres", false, `doas -n -u 'postgres' sh -c 'id'`},
+	}
+	for _, tt := range tests {
+		b := &Become{method: tt.method, user: tt.user}
+		got := b.wrap("id", tt.prompt)
+		if got != tt.want {
+			t.Errorf("%s user %q prompt %t: got %s, want %s", tt.method, tt.user, tt.prompt, got, tt.want)
+		}
+	}
+
+	// the command reaches the shell as is
+	b := &Become{method: BecomeSudo}
+	got := b.wrap(`df -P / | awk '{print $5}'`, false)
+	want := `sudo -n -- sh -c 'df -P / | awk '\''{print $5}'\'''`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestNewBecome(t *testing.T) {
+	tests := []struct {
+		method   string
+		password string
+		ok       bool
+	}{
+		{BecomeSudo, "", true},
+		{BecomeSudo, "pw", true},
+		{BecomeDoas, "", true},
+		{BecomeDoas, "pw", false},
+		{BecomeNone, "", false},
+		{"su", "", false},
+	}
+	for _, tt := range tests {
+		_, err := NewBecome(&Local{}, tt.method, "", tt.password)
+		if (err == nil) != tt.ok {
+			t.Errorf("NewBecome(%s, %q) error = %v, want ok %t", tt.method, tt.password, err, tt.ok)
+		}
+	}
+}
+
+func TestBecomePassword(t *testing.T) {
+	ok := &Result{Stdout: "out", ExitCode: 0}
+	noPassword := &Result{Stderr: "sudo: a password is required\n", ExitCode: 1}
+	prompted := &Result{Stdout: "out", Stderr: becomePrompt, ExitCode: 0}
+	badPassword := &Result{Stderr: becomePrompt + "Sorry, try again.\n" + becomePrompt + "sudo: 3 incorrect password attempts\n", ExitCode: 1}
+	notPermitted := &Result{Stderr: "user is not in the sudoers file.\nsudo: user is not allowed to execute '/bin/sh'\n", ExitCode: 1}
+	commandFailed := &Result{Stderr: "ls: cannot access 'x'\n", ExitCode: 2}
+
+	tests := []struct {
+		name     string
+		method   string
+		password string
+		results  []*Result
+		// inputs of each run
+		inputs []string
+		err    string
+	}{
+		{"passwordless", BecomeSudo, "pw", []*Result{ok}, []string{"in"}, ""},
+		{"password", BecomeSudo, "pw", []*Result{noPassword, prompted}, []string{"in", "pw\nin"}, ""},
+		{"no password configured", BecomeSudo, "", []*Result{noPassword}, []string{"in"}, "none is configured"},
+		{"bad password", BecomeSudo, "bad", []*Result{noPassword, badPassword}, []string{"in", "bad\nin"}, "incorrect password"},
+		{"not permitted", BecomeSudo, "pw", []*Result{notPermitted}, []string{"in"}, "not permitted"},
+		{"command failed", BecomeSudo, "pw", []*Result{commandFailed}, []string{"in"}, "exit code: 2"},
+		{"doas", BecomeDoas, "", []*Result{{Stderr: "doas: Authorization required\n", ExitCode: 1}}, []string{"in"}, "only passwordless"},
+	}
+	for _, tt := range tests {
+		f := &fakeTransport{results: tt.results}
+		b, err := NewBecome(f, tt.method, "", tt.password)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		res, err := b.ExecuteWithInput(t.Context(), "ls x", "in")
+		if len(tt.err) < 1 && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if len(tt.err) > 0 && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+		}
+		if strings.Join(f.inputs, "|") != strings.Join(tt.inputs, "|") {
+			t.Errorf("%s: inputs %q, want %q", tt.name, f.inputs, tt.inputs)
+		}
+		if strings.Contains(res.Stderr, becomePrompt) {
+			t.Errorf("%s: the prompt is left in stderr: %q", tt.name, res.Stderr)
+		}
+	}
+}
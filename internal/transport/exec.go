@@ -0,0 +1,91 @@
+// Copyright (c) 2021 deadc0de6
+
+package transport
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Exec a transport running the commands through an exec
+// prefix taking a shell command as its last argument,
+// like "docker exec -i web sh -c" or "kubectl exec -i pod -- sh -c"
+type Exec struct {
+	local  *Local
+	prefix string
+}
+
+// wrap returns the local command running cmd through the prefix
+func (e *Exec) wrap(cmd string) string {
+	return e.prefix + " " + quote(cmd)
+}
+
+// Execute executes a command through the prefix
+func (e *Exec) Execute(ctx context.Context, cmd string) (*Result, error) {
+	return e.ExecuteWithInput(ctx, cmd, "")
+}
+
+// ExecuteWithInput executes a command through the
+// prefix with input written to its stdin
+func (e *Exec) ExecuteWithInput(ctx context.Context, cmd string, input string) (*Result, error) {
+	log.Debugf("exec run: \"%s\"", cmd)
+	res, err := e.local.ExecuteWithInput(ctx, e.wrap(cmd), input)
+	if err != nil {
+		log.Debugf("exec command \"%s\" failed: %v (%s)", cmd, err, res.Stderr)
+	}
+	return res, err
+}
+
+// Copy copies a file by writing it to the stdin of cat
+// through the prefix, it fails if the prefix does not
+// keep stdin open (docker -i) as nothing gets written
+func (e *Exec) Copy(ctx context.Context, localPath string, remotePath string, rights string) error {
+	mode, err := parseRights(rights)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+	// written aside and renamed to replace it atomically, the
+	// size is checked as a prefix without -i writes an empty file
+	tmp := path.Join(path.Dir(remotePath), "."+path.Base(remotePath)+".checkah")
+	cmd := fmt.Sprintf("cat > %s && test \"$(wc -c < %s)\" -eq %d || { rm -f %s; echo \"short write\" >&2; exit 1; }; chmod %04o %s && mv -f %s %s",
+		quote(tmp), quote(tmp), len(data), quote(tmp), mode, quote(tmp), quote(tmp), quote(remotePath))
+	res, err := e.local.ExecuteWithInput(ctx, e.wrap(cmd), string(data))
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(res.Stderr))
+	}
+	return nil
+}
+
+// Mkdir creates a directory and its parents
+func (e *Exec) Mkdir(ctx context.Context, remotePath string) error {
+	res, err := e.Execute(ctx, "mkdir -p "+quote(remotePath))
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(res.Stderr))
+	}
+	return nil
+}
+
+// Close does nothing, each command is its own process
+func (e *Exec) Close() {}
+
+// NewExec creates a transport running the commands through prefix
+func NewExec(prefix string) (*Exec, error) {
+	prefix = strings.TrimSpace(prefix)
+	if len(prefix) < 1 {
+		return nil, fmt.Errorf("\"exec\" option required")
+	}
+	e := &Exec{
+		local:  &Local{},
+		prefix: prefix,
+	}
+	return e, nil
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2021 deadc0de6
+
+package transport
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewExec(t *testing.T) {
+	tests := []struct {
+		prefix string
+		ok     bool
+	}{
+		{"docker exec -i web sh -c", true},
+		{"  sh -c ", true},
+		{"", false},
+		{"   ", false},
+	}
+	for _, tt := range tests {
+		_, err := NewExec(tt.prefix)
+		if (err == nil) != tt.ok {
+			t.Errorf("NewExec(%q) error = %v, want ok %t", tt.prefix, err, tt.ok)
+		}
+	}
+}
+
+func TestExecWrap(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"echo plain", "plain\n"},
+		{`echo 'single quoted'`, "single quoted\n"},
+		{`echo "it's"`, "it's\n"},
+		// expanded by the inner shell only
+		{`X=inner; echo "$X"`, "inner\n"},
+		{`echo '$HOME' "$(echo sub)"`, "$HOME sub\n"},
+		{`printf '%s\n' a b | wc -l`, "2\n"},
+	}
+	e, err := NewExec("X=outer sh -c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		res, err := e.Execute(t.Context(), tt.cmd)
+		if err != nil {
+			t.Errorf("%s: %v", tt.cmd, err)
+			continue
+		}
+		if strings.TrimLeft(res.Stdout, " ") != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.cmd, res.Stdout, tt.want)
+		}
+	}
+}
+
+func TestExecCopy(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	content := "#!/bin/sh\necho 'copied'\n"
+	err := os.WriteFile(src, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		prefix string
+		dst    string
+		ok     bool
+	}{
+		{"copy", "sh -c", "dst", true},
+		{"replace", "sh -c", "dst", true},
+		{"quoted path", "sh -c", "it's a file", true},
+		// the prefix does not pass stdin, like docker exec without -i
+		{"no stdin", "exec </dev/null; sh -c", "nostdin", false},
+		{"bad directory", "sh -c", "missing/dst", false},
+	}
+	for _, tt := range tests {
+		e, err := NewExec(tt.prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := filepath.Join(dir, tt.dst)
+		err = e.Copy(t.Context(), src, dst, "0750")
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error = %v, want ok %t", tt.name, err, tt.ok)
+			continue
+		}
+
+		// no temporary file left
+		tmp := filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+".checkah")
+		if _, serr := os.Stat(tmp); serr == nil {
+			t.Errorf("%s: temporary file left", tt.name)
+		}
+		if !tt.ok {
+			if _, serr := os.Stat(dst); serr == nil {
+				t.Errorf("%s: destination written", tt.name)
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(dst)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if string(data) != content {
+			t.Errorf("%s: got %q, want %q", tt.name, data, content)
+		}
+		info, err := os.Stat(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0750 {
+			t.Errorf("%s: mode %o, want 750", tt.name, info.Mode().Perm())
+		}
+	}
+
+	// a short write is reported
+	e, err := NewExec("exec </dev/null; sh -c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.Copy(t.Context(), src, filepath.Join(dir, "short"), "0644")
+	if err == nil || !strings.Contains(err.Error(), "short write") {
+		t.Errorf("error = %v, want a short write", err)
+	}
+}
+
+func TestExecMkdir(t *testing.T) {
+	e, err := NewExec("sh -c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "a b", "it's")
+	err = e.Mkdir(t.Context(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		t.Errorf("%s not created: %v", dir, err)
+	}
+}
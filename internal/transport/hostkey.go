@@ -0,0 +1,175 @@
+// Copyright (c) 2021 deadc0de6
+
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var (
+	// serializes the writes to the trust on first use file
+	tofuMut sync.Mutex
+)
+
+// HostKeyError the host key does not match
+// the pinned or the known one
+type HostKeyError struct {
+	Host        string
+	Fingerprint string
+}
+
+func (e *HostKeyError) Error() string {
+	return fmt.Sprintf("host key for %s has changed to %s, possible man-in-the-middle attack", e.Host, e.Fingerprint)
+}
+
+// emptyKey a key that matches no known key
+type emptyKey struct{}
+
+func (emptyKey) Type() string                        { return "checkah-empty" }
+func (emptyKey) Marshal() []byte                     { return []byte{} }
+func (emptyKey) Verify([]byte, *ssh.Signature) error { return fmt.Errorf("empty key") }
+
+// knownHostsFiles returns the existing known_hosts files
+func knownHostsFiles(opts *SSHOptions) []string {
+	paths := opts.KnownHosts
+	if len(paths) < 1 {
+		paths = append(paths, filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts"))
+	}
+	if len(opts.TOFU) > 0 {
+		paths = append(paths, opts.TOFU)
+	}
+
+	// missing files are ignored
+	var files []string
+	for _, path := range paths {
+		if fileExists(path) {
+			log.Debugf("SSH reading known_hosts file from \"%s\"", path)
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// knownKeys returns the known keys of a host
+func knownKeys(cb ssh.HostKeyCallback, addr string) []knownhosts.KnownKey {
+	var keyErr *knownhosts.KeyError
+	err := cb(addr, &net.TCPAddr{}, emptyKey{})
+	if !errors.As(err, &keyErr) {
+		return nil
+	}
+	return keyErr.Want
+}
+
+// knownAlgorithms returns the algorithms of the known keys of a host
+// so that the server does not present another type of key
+// (https://github.com/golang/go/issues/28870)
+func knownAlgorithms(keys []knownhosts.KnownKey) []string {
+	var algos []string
+	for _, k := range keys {
+		switch k.Key.Type() {
+		case ssh.KeyAlgoRSA:
+			algos = append(algos, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA)
+		default:
+			algos = append(algos, k.Key.Type())
+		}
+	}
+	return algos
+}
+
+// trust records a new host key in the trust on first use file
+func trust(path string, addr string, key ssh.PublicKey) error {
+	tofuMut.Lock()
+	defer tofuMut.Unlock()
+
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(addr)}, key)
+	_, err = fmt.Fprintln(f, line)
+	if err != nil {
+		return err
+	}
+	log.Infof("SSH trusting new host key %s for %s", ssh.FingerprintSHA256(key), addr)
+	return nil
+}
+
+// pinnedCallback accepts only the pinned fingerprints
+func pinnedCallback(pins []string) ssh.HostKeyCallback {
+	return func(addr string, _ net.Addr, key ssh.PublicKey) error {
+		fp := ssh.FingerprintSHA256(key)
+		for _, pin := range pins {
+			if strings.TrimSpace(pin) == fp {
+				return nil
+			}
+		}
+		return &HostKeyError{Host: addr, Fingerprint: fp}
+	}
+}
+
+// hostKeyCallback returns the host key callback with the
+// algorithms to ask for, keys are either ignored (insecure),
+// pinned, trusted on first use or in the known_hosts files
+func hostKeyCallback(opts *SSHOptions) (ssh.HostKeyCallback, []string, error) {
+	if opts.Insecure {
+		log.Debug("SSH insecure knownhost")
+		return ssh.InsecureIgnoreHostKey(), nil, nil
+	}
+
+	if len(opts.HostKeys) > 0 {
+		log.Debugf("SSH pinned host keys for %s", opts.Address())
+		return pinnedCallback(opts.HostKeys), nil, nil
+	}
+
+	files := knownHostsFiles(opts)
+	if len(files) < 1 && len(opts.TOFU) < 1 {
+		return nil, nil, fmt.Errorf("no known_hosts file found")
+	}
+
+	var cb ssh.HostKeyCallback
+	if len(files) > 0 {
+		var err error
+		cb, err = knownhosts.New(files...)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		cb = func(_ string, _ net.Addr, _ ssh.PublicKey) error {
+			return &knownhosts.KeyError{}
+		}
+	}
+	algos := knownAlgorithms(knownKeys(cb, opts.Address()))
+
+	return func(addr string, remote net.Addr, key ssh.PublicKey) error {
+		log.Debugf("SSH checking knownhost for \"%s\" (\"%v\")", addr, remote)
+		err := cb(addr, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+		if len(keyErr.Want) > 0 {
+			// known host with another key
+			return &HostKeyError{Host: addr, Fingerprint: ssh.FingerprintSHA256(key)}
+		}
+		if len(opts.TOFU) < 1 {
+			return err
+		}
+		return trust(opts.TOFU, addr, key)
+	}, algos, nil
+}
@@ -4,36 +4,115 @@ package transport
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 )
 
-// Local the localhost fake object
+const (
+	// time left to the killed commands to release their output
+	killWait = time.Second
+)
+
+// Local the localhost transport
 type Local struct{}
 
-// Execute executes a command through SSH
-// returns stdout, stderr, error
-func (l *Local) Execute(cmd string) (string, string, error) {
+// Execute executes a command locally
+func (l *Local) Execute(ctx context.Context, cmd string) (*Result, error) {
+	return l.ExecuteWithInput(ctx, cmd, "")
+}
+
+// ExecuteWithInput executes a command locally
+// with input written to its stdin, the command and
+// its children are killed when the context is done
+func (l *Local) ExecuteWithInput(ctx context.Context, cmd string, input string) (*Result, error) {
 	var stdout, stderr bytes.Buffer
 
-	c := exec.Command("bash", "-c", cmd)
+	start := time.Now()
+	c := exec.CommandContext(ctx, "bash", "-c", cmd)
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	c.Cancel = func() error {
+		return syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+	}
+	c.WaitDelay = killWait
 	c.Stdout = &stdout
 	c.Stderr = &stderr
+	if len(input) > 0 {
+		c.Stdin = strings.NewReader(input)
+	}
 	err := c.Run()
+	res := newResult(stdout.String(), stderr.String(), start)
+	if ctx.Err() != nil {
+		res.TransportError = true
+		return res, fmt.Errorf("command killed: %w", ctx.Err())
+	}
+
+	var e *exec.ExitError
+	if errors.As(err, &e) {
+		res.ExitCode = e.ExitCode()
+		if res.ExitCode < 0 {
+			return res, fmt.Errorf("command %v", e)
+		}
+		return res, &ExitError{Code: res.ExitCode}
+	}
 	if err != nil {
-		return "", "", err
+		res.TransportError = true
+		return res, err
 	}
 
-	return stdout.String(), stderr.String(), nil
+	res.ExitCode = 0
+	return res, nil
 }
 
-// Copy fakes copy
-func (l *Local) Copy(string, string, string) error {
-	return nil
+// Copy copies a file, the destination is
+// replaced atomically with a temporary file
+func (l *Local) Copy(ctx context.Context, srcPath string, dstPath string, rights string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	mode, err := parseRights(rights)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dstPath), "."+filepath.Base(dstPath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Chmod(mode)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dstPath)
 }
 
-// Mkdir fakes mkdir
-func (l *Local) Mkdir(string) error {
-	return nil
+// Mkdir creates a directory and its parents
+func (l *Local) Mkdir(ctx context.Context, path string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return os.MkdirAll(path, 0755)
 }
 
 // Close fakes closing connection
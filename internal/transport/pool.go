@@ -0,0 +1,280 @@
+// Copyright (c) 2021 deadc0de6
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// used when the host has no connection timeout
+const poolCheckTimeout = 10 * time.Second
+
+// a pooled SSH connection
+type pooledConn struct {
+	key      string
+	ssh      *SSH
+	client   *ssh.Client
+	opts     *SSHOptions
+	inUse    bool
+	lastUsed time.Time
+	closed   bool
+	done     chan struct{}
+	// found dead while in use, removed when released
+	dead bool
+}
+
+// Pool keeps the SSH connections open between runs,
+// they are kept alive and redialed when found dead
+type Pool struct {
+	keepalive time.Duration
+	maxIdle   int
+	conns     map[string]*pooledConn
+	mut       sync.Mutex
+}
+
+// Pooled an SSH connection of the pool, closing
+// it gives it back to the pool
+type Pooled struct {
+	*SSH
+	pool *Pool
+	conn *pooledConn
+}
+
+// poolKey identifies a connection by its
+// user, host and port and the jump hosts ones
+func poolKey(opts *SSHOptions) string {
+	var hops []string
+	for _, jump := range opts.Jumps {
+		hops = append(hops, jump.String())
+	}
+	hops = append(hops, opts.String())
+	return strings.Join(hops, ",")
+}
+
+// keepaliveLoop sends keepalives until the connection is dead
+// or removed from the pool, a dead connection in use is
+// removed when it is released
+func (p *Pool) keepaliveLoop(c *pooledConn) {
+	ticker := time.NewTicker(p.keepalive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+		err := keepalive(c.client, p.keepalive)
+		if err == nil {
+			continue
+		}
+		log.Debugf("SSH pool: %s is dead: %v", c.key, err)
+		p.mut.Lock()
+		if c.inUse {
+			c.dead = true
+		} else {
+			p.remove(c)
+		}
+		p.mut.Unlock()
+		return
+	}
+}
+
+// remove closes and removes a connection, the lock must be held
+func (p *Pool) remove(c *pooledConn) {
+	if p.conns[c.key] == c {
+		delete(p.conns, c.key)
+	}
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.done)
+	c.ssh.Close()
+}
+
+// evict closes the least recently used idle
+// connections above the cap, the lock must be held
+func (p *Pool) evict() {
+	var idle []*pooledConn
+	for _, c := range p.conns {
+		if !c.inUse {
+			idle = append(idle, c)
+		}
+	}
+	if p.maxIdle < 1 || len(idle) <= p.maxIdle {
+		return
+	}
+	sort.Slice(idle, func(i, j int) bool {
+		return idle[i].lastUsed.Before(idle[j].lastUsed)
+	})
+	for _, c := range idle[:len(idle)-p.maxIdle] {
+		log.Debugf("SSH pool: closing idle %s", c.key)
+		p.remove(c)
+	}
+}
+
+// dial opens a new connection and adds it to the pool
+func (p *Pool) dial(opts *SSHOptions) (*pooledConn, error) {
+	key := poolKey(opts)
+	t, err := NewSSH(opts)
+	if err != nil {
+		return nil, err
+	}
+	c := &pooledConn{
+		key:    key,
+		ssh:    t,
+		client: t.client,
+		opts:   opts,
+		inUse:  true,
+		done:   make(chan struct{}),
+	}
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	old, ok := p.conns[key]
+	if ok && old.inUse {
+		// the same host used twice in a run, not pooled
+		c.key = ""
+		return c, nil
+	}
+	if ok {
+		p.remove(old)
+	}
+	p.conns[key] = c
+	if p.keepalive > 0 {
+		go p.keepaliveLoop(c)
+	}
+	return c, nil
+}
+
+// Get returns a connection to the host, an idle one
+// if it is still alive, a new one otherwise
+func (p *Pool) Get(opts *SSHOptions) (*Pooled, error) {
+	key := poolKey(opts)
+
+	p.mut.Lock()
+	c, ok := p.conns[key]
+	if ok && !c.inUse {
+		c.inUse = true
+	} else {
+		c = nil
+	}
+	p.mut.Unlock()
+
+	if c != nil {
+		timeout := time.Duration(opts.Timeout) * time.Second
+		if timeout <= 0 {
+			timeout = poolCheckTimeout
+		}
+		err := keepalive(c.client, timeout)
+		if err == nil {
+			log.Debugf("SSH pool: reusing %s", key)
+			c.opts = opts
+			return &Pooled{SSH: c.ssh, pool: p, conn: c}, nil
+		}
+		log.Debugf("SSH pool: %s is dead, reconnecting: %v", key, err)
+		p.mut.Lock()
+		p.remove(c)
+		p.mut.Unlock()
+	}
+
+	c, err := p.dial(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Pooled{SSH: c.ssh, pool: p, conn: c}, nil
+}
+
+// release gives a connection back to the pool
+func (p *Pool) release(c *pooledConn) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if len(c.key) < 1 || c.closed {
+		c.ssh.Close()
+		return
+	}
+	if c.dead {
+		log.Debugf("SSH pool: closing dead %s", c.key)
+		p.remove(c)
+		return
+	}
+	c.inUse = false
+	c.lastUsed = time.Now()
+	p.evict()
+}
+
+// reconnect replaces a broken connection with a new one
+func (t *Pooled) reconnect() error {
+	log.Debugf("SSH pool: reconnecting %s", t.conn.key)
+	p := t.pool
+	p.mut.Lock()
+	if len(t.conn.key) > 0 {
+		p.remove(t.conn)
+	} else {
+		t.conn.ssh.Close()
+	}
+	opts := t.conn.opts
+	p.mut.Unlock()
+
+	c, err := p.dial(opts)
+	if err != nil {
+		return err
+	}
+	t.SSH = c.ssh
+	t.conn = c
+	return nil
+}
+
+// Execute executes a command
+func (t *Pooled) Execute(ctx context.Context, cmd string) (*Result, error) {
+	return t.ExecuteWithInput(ctx, cmd, "")
+}
+
+// ExecuteWithInput executes a command, reconnecting once
+// if no session could be opened on the connection
+func (t *Pooled) ExecuteWithInput(ctx context.Context, cmd string, input string) (*Result, error) {
+	res, err := t.SSH.ExecuteWithInput(ctx, cmd, input)
+	if !errors.Is(err, errNoSession) {
+		return res, err
+	}
+	rerr := t.reconnect()
+	if rerr != nil {
+		log.Debugf("SSH pool: reconnect failed: %v", rerr)
+		return res, err
+	}
+	return t.SSH.ExecuteWithInput(ctx, cmd, input)
+}
+
+// Close gives the connection back to the pool
+func (t *Pooled) Close() {
+	t.pool.release(t.conn)
+}
+
+// Close closes all the connections
+func (p *Pool) Close() {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	for _, c := range p.conns {
+		p.remove(c)
+	}
+}
+
+// NewPool creates a pool sending keepalives at the
+// given interval (none if zero) and keeping at most
+// maxIdle idle connections (unlimited if zero)
+func NewPool(keepalive time.Duration, maxIdle int) *Pool {
+	p := &Pool{
+		keepalive: keepalive,
+		maxIdle:   maxIdle,
+		conns:     make(map[string]*pooledConn),
+	}
+	return p
+}
@@ -0,0 +1,314 @@
+// Copyright (c) 2021 deadc0de6
+
+package transport
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer an SSH server accepting any user with
+// the password "pw" and answering the keepalives
+type testServer struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+	mut      sync.Mutex
+	conns    []net.Conn
+	accepted int
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mut.Lock()
+		s.conns = append(s.conns, conn)
+		s.accepted++
+		s.mut.Unlock()
+		go func() {
+			_, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+			if err != nil {
+				return
+			}
+			go func() {
+				for req := range reqs {
+					_ = req.Reply(req.Type == keepaliveRequest, nil)
+				}
+			}()
+			for ch := range chans {
+				_ = ch.Reject(ssh.Prohibited, "no session")
+			}
+		}()
+	}
+}
+
+// drop closes all the connections without a goodbye
+func (s *testServer) drop() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *testServer) count() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.accepted
+}
+
+func (s *testServer) options(user string) *SSHOptions {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return &SSHOptions{
+		Host:     host,
+		Port:     port,
+		User:     user,
+		Password: "pw",
+		Timeout:  5,
+		Insecure: true,
+	}
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	// no key nor agent of the user
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) != "pw" {
+				return nil, ssh.ErrNoAuth
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{listener: listener, config: config}
+	go s.serve()
+	t.Cleanup(func() {
+		listener.Close()
+		s.drop()
+	})
+	return s
+}
+
+// pooled returns the number of connections in the pool
+func pooled(p *Pool) int {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	return len(p.conns)
+}
+
+// waitFor waits for cond to be true
+func waitFor(t *testing.T, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestPoolReuse(t *testing.T) {
+	s := newTestServer(t)
+	p := NewPool(0, 0)
+	defer p.Close()
+
+	for i := 0; i < 3; i++ {
+		c, err := p.Get(s.options("a"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+	}
+	if s.count() != 1 {
+		t.Errorf("%d connections, want 1 reused", s.count())
+	}
+
+	// another user is another connection
+	c, err := p.Get(s.options("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if s.count() != 2 || pooled(p) != 2 {
+		t.Errorf("%d connections, %d pooled, want 2 and 2", s.count(), pooled(p))
+	}
+}
+
+func TestPoolInUse(t *testing.T) {
+	s := newTestServer(t)
+	p := NewPool(0, 0)
+	defer p.Close()
+
+	// the same host twice at once, the second is not pooled
+	first, err := p.Get(s.options("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.Get(s.options("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.conn == second.conn || s.count() != 2 {
+		t.Errorf("%d connections, want 2", s.count())
+	}
+	second.Close()
+	if second.client != nil {
+		t.Errorf("the unpooled connection is not closed")
+	}
+	first.Close()
+	if pooled(p) != 1 {
+		t.Errorf("%d pooled, want 1", pooled(p))
+	}
+}
+
+func TestPoolMaxIdle(t *testing.T) {
+	s := newTestServer(t)
+	p := NewPool(0, 2)
+	defer p.Close()
+
+	var conns []*Pooled
+	for _, user := range []string{"a", "b", "c"} {
+		c, err := p.Get(s.options(user))
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, c)
+	}
+	for _, c := range conns {
+		c.Close()
+	}
+	if pooled(p) != 2 {
+		t.Errorf("%d pooled, want 2", pooled(p))
+	}
+	// the least recently used one is closed
+	p.mut.Lock()
+	_, ok := p.conns[poolKey(s.options("a"))]
+	p.mut.Unlock()
+	if ok {
+		t.Errorf("the least recently used connection is kept")
+	}
+}
+
+func TestPoolKeepalive(t *testing.T) {
+	s := newTestServer(t)
+	p := NewPool(20*time.Millisecond, 0)
+	defer p.Close()
+
+	// an idle dead connection is evicted
+	c, err := p.Get(s.options("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	s.drop()
+	if !waitFor(t, func() bool { return pooled(p) == 0 }) {
+		t.Errorf("the idle dead connection is not evicted")
+	}
+
+	// one found dead while in use when it is released
+	c, err = p.Get(s.options("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.drop()
+	dead := func() bool {
+		p.mut.Lock()
+		defer p.mut.Unlock()
+		return c.conn.dead
+	}
+	if !waitFor(t, dead) {
+		t.Fatalf("the dead connection in use is not noticed")
+	}
+	if pooled(p) != 1 {
+		t.Errorf("%d pooled, want the one in use", pooled(p))
+	}
+	c.Close()
+	if pooled(p) != 0 || !c.conn.closed {
+		t.Errorf("the dead connection is not evicted on release")
+	}
+
+	// and the next one redials
+	c, err = p.Get(s.options("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if s.count() != 3 {
+		t.Errorf("%d connections, want 3", s.count())
+	}
+}
+
+func TestPoolRedial(t *testing.T) {
+	s := newTestServer(t)
+	p := NewPool(0, 0)
+	defer p.Close()
+
+	c, err := p.Get(s.options("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	s.drop()
+
+	// without keepalives, found dead when reused
+	c, err = p.Get(s.options("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if s.count() != 2 || pooled(p) != 1 {
+		t.Errorf("%d connections, %d pooled, want 2 and 1", s.count(), pooled(p))
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	s := newTestServer(t)
+	p := NewPool(20*time.Millisecond, 0)
+
+	var conns []*pooledConn
+	for _, user := range []string{"a", "b"} {
+		c, err := p.Get(s.options(user))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+		conns = append(conns, c.conn)
+	}
+	p.Close()
+	if pooled(p) != 0 {
+		t.Errorf("%d pooled after close", pooled(p))
+	}
+	for _, c := range conns {
+		if !c.closed {
+			t.Errorf("%s not closed", c.key)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2021 deadc0de6
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/pkg/sftp"
+	log "github.com/sirupsen/logrus"
+)
+
+// errNoSFTP the remote has no sftp subsystem
+var errNoSFTP = errors.New("sftp subsystem not available")
+
+// parseRights parses octal file rights like "755"
+func parseRights(rights string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(rights, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("bad file rights \"%s\"", rights)
+	}
+	return os.FileMode(mode), nil
+}
+
+// withSFTP runs fn over the sftp subsystem, the
+// client is closed when done or when ctx is
+func (t *SSH) withSFTP(ctx context.Context, fn func(*sftp.Client) error) error {
+	if t.noSFTP {
+		return errNoSFTP
+	}
+	client, err := sftp.NewClient(t.client)
+	if err != nil {
+		log.Debugf("SFTP not available, using scp: %v", err)
+		t.noSFTP = true
+		return errNoSFTP
+	}
+	defer client.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(client)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// unblocks fn
+		client.Close()
+		return fmt.Errorf("SFTP transfer killed: %w", ctx.Err())
+	}
+}
+
+// sftpMkdir creates a directory and its parents over sftp
+func (t *SSH) sftpMkdir(ctx context.Context, remotePath string) error {
+	return t.withSFTP(ctx, func(client *sftp.Client) error {
+		log.Debugf("SFTP mkdir \"%s\"", remotePath)
+		return client.MkdirAll(remotePath)
+	})
+}
+
+// sftpCopy writes data to a remote file over sftp
+func (t *SSH) sftpCopy(ctx context.Context, data []byte, remotePath string, mode os.FileMode) error {
+	return t.withSFTP(ctx, func(client *sftp.Client) error {
+		log.Debugf("SFTP copy %d bytes to \"%s\"", len(data), remotePath)
+		f, err := client.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		err = f.Chmod(mode)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
@@ -2,27 +2,20 @@
 
 package transport
 
-// knownhost key mismatch issue:
-// - https://github.com/golang/go/issues/28870
-// - https://github.com/golang/go/issues/36126
-// prepend your key generated with below commands to `~/.ssh/known_hosts`
-// 		ssh-keyscan -p 22 -H <host>
-
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"path"
-	"path/filepath"
 	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/crypto/ssh"
-	"golang.org/x/crypto/ssh/agent"
-	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 const (
@@ -30,19 +23,19 @@ const (
 	scpCommand = "scp -tr %s"
 	connRetry  = 3
 	retrySleep = 2
+	// the request OpenSSH sends as keepalive
+	keepaliveRequest = "keepalive@openssh.com"
 )
 
-var (
-	defaultKeys = []string{
-		"id_rsa",
-		"id_ed25519",
-	}
-)
+var errNoSession = errors.New("cannot open a session")
 
 // SSH the ssh struct
 type SSH struct {
 	config *ssh.ClientConfig
 	client *ssh.Client
+	jumps  []*ssh.Client
+	// fall back to scp
+	noSFTP bool
 }
 
 func fileExists(path string) bool {
@@ -50,177 +43,137 @@ func fileExists(path string) bool {
 	return !os.IsNotExist(err)
 }
 
-func checkKnownHosts() (ssh.HostKeyCallback, error) {
-	path := filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts")
-	log.Debugf("SSH reading known_hosts file from \"%s\"", path)
-
-	f, err := knownhosts.New(path)
+// runSession runs a command in a session, the remote
+// command is killed when the context is done
+func runSession(ctx context.Context, session *ssh.Session, cmd string) error {
+	err := session.Start(cmd)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
 
-	return func(addr string, remote net.Addr, key ssh.PublicKey) error {
-		log.Debugf("SSH checking knownhost for \"%s\" (\"%v\")", addr, remote)
-		return f(addr, remote, key)
-	}, nil
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		// not all servers support signals, closing
+		// the channel hangs up the remote command
+		_ = session.Signal(ssh.SIGKILL)
+		session.Close()
+		// the output is copied until Wait returns
+		<-done
+		return fmt.Errorf("SSH command killed: %w", ctx.Err())
+	}
 }
 
-func loadAgent() ssh.AuthMethod {
-	path := os.Getenv("SSH_AUTH_SOCK")
-	if len(path) < 1 {
-		log.Debug("SSH no auth socket found")
-		return nil
-	}
-	sock, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK"))
+// scp runs scp in sink mode on the remote, feeding it with write
+func (t *SSH) scp(ctx context.Context, remoteDir string, write func(io.Writer) error) error {
+	// get an ssh session
+	session, err := t.client.NewSession()
 	if err != nil {
-		log.Debug(err)
-		return nil
+		return err
 	}
+	defer session.Close()
 
-	a := agent.NewClient(sock)
-	if a == nil {
-		log.Debug("SSH no signers for agent")
-		return nil
+	w, err := session.StdinPipe()
+	if err != nil {
+		return err
 	}
+	var out bytes.Buffer
+	session.Stdout = &out
 
-	log.Debug("SSH agent socket found")
-
-	/*
-		loadedKeys, err := a.List()
+	go func() {
+		defer w.Close()
+		err := write(w)
 		if err != nil {
-			log.Debug(err)
-			return nil
-		}
-		for _, key := range loadedKeys {
-			log.Debugf(key.String())
+			log.Error(err)
 		}
-	*/
-
-	//return ssh.PublicKeysCallback(a.Signers)
-
-	signers, err := a.Signers()
-	if err != nil {
-		log.Debug(err)
-		return nil
-	}
-	return ssh.PublicKeys(signers...)
-
-}
-
-func loadKeyfile(path string) ssh.AuthMethod {
-	log.Debugf("SSH loading key from \"%s\"", path)
-	key, err := os.ReadFile(path)
-	if err != nil {
-		return nil
-	}
+	}()
 
-	signer, err := ssh.ParsePrivateKey(key)
+	cmd := fmt.Sprintf(scpCommand, remoteDir)
+	err = runSession(ctx, session, cmd)
 	if err != nil {
-		return nil
+		return fmt.Errorf("%s: %w", out.String(), err)
 	}
-	return ssh.PublicKeys(signer)
+	return nil
 }
 
-// Mkdir mkdir over ssh
-func (t *SSH) Mkdir(remotePath string) error {
-	// get an ssh session
-	session, err := t.client.NewSession()
-	if err != nil {
+// Mkdir creates a directory over sftp, or scp if not available
+func (t *SSH) Mkdir(ctx context.Context, remotePath string) error {
+	err := t.sftpMkdir(ctx, remotePath)
+	if !errors.Is(err, errNoSFTP) {
 		return err
 	}
-	defer session.Close()
 
 	remoteDir := path.Dir(remotePath)
 	remoteBase := path.Base(remotePath)
 
-	go func() {
-		w, err := session.StdinPipe()
-		if err != nil {
-			log.Error(err)
-			return
-		}
-		defer w.Close()
+	return t.scp(ctx, remoteDir, func(w io.Writer) error {
 		// mkdir
-		_, err = fmt.Fprintln(w, "D0755", 0, remoteBase)
-		if err != nil {
-			log.Error(err)
-			return
-		}
-	}()
+		_, err := fmt.Fprintln(w, "D0755", 0, remoteBase)
+		return err
+	})
+}
 
-	cmd := fmt.Sprintf(scpCommand, remoteDir)
-	out, err := session.CombinedOutput(cmd)
+// Copy copies a file to the remote over sftp, or scp if not available
+func (t *SSH) Copy(ctx context.Context, localPath string, remotePath string, rights string) error {
+	mode, err := parseRights(rights)
 	if err != nil {
-		return fmt.Errorf("%s: %v", out, err)
+		return err
 	}
 
-	return nil
-}
-
-// Copy scp a file to the remote
-func (t *SSH) Copy(localPath string, remotePath string, rights string) error {
 	// read local file
 	data, err := os.ReadFile(localPath)
 	if err != nil {
 		return err
 	}
 
-	// get an ssh session
-	session, err := t.client.NewSession()
-	if err != nil {
+	err = t.sftpCopy(ctx, data, remotePath, mode)
+	if !errors.Is(err, errNoSFTP) {
 		return err
 	}
-	defer session.Close()
 
 	remoteDir := path.Dir(remotePath)
 	remoteBase := path.Base(remotePath)
 
-	// content writer
-	go func() {
-		// open stdin session
-		w, err := session.StdinPipe()
-		if err != nil {
-			log.Error(err)
-			return
-		}
-		defer w.Close()
-
+	return t.scp(ctx, remoteDir, func(w io.Writer) error {
 		// provide filename
-		r := fmt.Sprintf("C0%s", rights)
-		_, err = fmt.Fprintln(w, r, len(data), remoteBase)
+		r := fmt.Sprintf("C%04o", mode)
+		_, err := fmt.Fprintln(w, r, len(data), remoteBase)
 		if err != nil {
-			return
+			return err
 		}
 
 		// write content
 		_, err = io.Copy(w, bytes.NewReader(data))
 		if err != nil {
-			return
+			return err
 		}
 
 		// terminate transfer
 		_, err = fmt.Fprint(w, "\x00") // transfer end with \x00
-		if err != nil {
-			return
-		}
-	}()
-
-	cmd := fmt.Sprintf(scpCommand, remoteDir)
-	out, err := session.CombinedOutput(cmd)
-	if err != nil {
-		return fmt.Errorf("%s: %v", out, err)
-	}
-
-	return nil
+		return err
+	})
 }
 
 // Execute executes a command through SSH
-// returns stdout, stderr, error
-func (t *SSH) Execute(cmd string) (string, string, error) {
+func (t *SSH) Execute(ctx context.Context, cmd string) (*Result, error) {
+	return t.ExecuteWithInput(ctx, cmd, "")
+}
+
+// ExecuteWithInput executes a command through SSH
+// with input written to its stdin
+func (t *SSH) ExecuteWithInput(ctx context.Context, cmd string, input string) (*Result, error) {
+	start := time.Now()
 	session, err := t.client.NewSession()
 	if err != nil {
 		log.Debugf("SSH new session for command \"%s\" failed: %v", cmd, err)
-		return "", "", err
+		res := newResult("", "", start)
+		res.TransportError = true
+		return res, fmt.Errorf("SSH session error: %w: %v", errNoSession, err)
 	}
 	log.Debugf("SSH new session opened for: \"%s\"", cmd)
 	defer session.Close()
@@ -230,44 +183,80 @@ func (t *SSH) Execute(cmd string) (string, string, error) {
 
 	session.Stdout = &stdout
 	session.Stderr = &stderr
+	if len(input) > 0 {
+		session.Stdin = strings.NewReader(input)
+	}
 
 	log.Debugf("SSH run: \"%s\"", cmd)
-	err = session.Run(cmd)
+	err = runSession(ctx, session, cmd)
+	res := newResult(stdout.String(), stderr.String(), start)
+	if ctx.Err() != nil {
+		log.Debugf("SSH command \"%s\" timed out", cmd)
+		res.TransportError = true
+		return res, err
+	}
 
 	_, ok := err.(*ssh.ExitMissingError)
 	if ok {
 		// ssh was successful but remote command didn't return an exit code
 		log.Debugf("SSH command \"%s\" failed with no exit code", cmd)
-		return stdout.String(), stderr.String(), fmt.Errorf("remote command is missing an exit code")
+		res.TransportError = true
+		return res, fmt.Errorf("remote command is missing an exit code")
 	}
 
 	e, ok := err.(*ssh.ExitError)
+	if ok && len(e.Signal()) > 0 {
+		log.Debugf("SSH command \"%s\" killed by signal %s", cmd, e.Signal())
+		return res, fmt.Errorf("remote command killed by signal %s", e.Signal())
+	}
 	if ok {
 		// an ExitError
-		retCode := e.ExitStatus()
-		log.Debugf("SSH command \"%s\" failed with exit code: %d", cmd, retCode)
-		log.Debugf("SSH command \"%s\" failed with stdout: %s", cmd, stdout.String())
-		log.Debugf("SSH command \"%s\" failed with stderr: %s", cmd, stderr.String())
-		return stdout.String(), stderr.String(), fmt.Errorf("remote command exit code: %d", retCode)
+		res.ExitCode = e.ExitStatus()
+		log.Debugf("SSH command \"%s\" failed with exit code: %d", cmd, res.ExitCode)
+		log.Debugf("SSH command \"%s\" failed with stdout: %s", cmd, res.Stdout)
+		log.Debugf("SSH command \"%s\" failed with stderr: %s", cmd, res.Stderr)
+		return res, &ExitError{Code: res.ExitCode}
 	}
 
 	if err != nil {
 		// any other type of error is an I/O error
 		log.Debugf("SSH command \"%s\" failed with I/O error: %v", cmd, err)
-		return stdout.String(), stderr.String(), fmt.Errorf("I/O error: %v", err)
+		res.TransportError = true
+		return res, fmt.Errorf("I/O error: %w", err)
 	}
 
 	// command ran successfully
-	log.Debugf("SSH command \"%s\" succeeded with stdout: %s", cmd, stdout.String())
-	log.Debugf("SSH command \"%s\" succeeded with stderr: %s", cmd, stderr.String())
-	return stdout.String(), stderr.String(), nil
+	res.ExitCode = 0
+	log.Debugf("SSH command \"%s\" succeeded in %s with stdout: %s", cmd, res.Duration, res.Stdout)
+	log.Debugf("SSH command \"%s\" succeeded with stderr: %s", cmd, res.Stderr)
+	return res, nil
 }
 
-// Close closes the SSH session
+// keepalive checks the connection is alive
+func keepalive(client *ssh.Client, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest(keepaliveRequest, true, nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("keepalive timed out after %s", timeout)
+	}
+}
+
+// Close closes the SSH session and the jump hosts ones
 func (t *SSH) Close() {
 	if t.client != nil {
 		t.client.Close()
+		t.client = nil
+	}
+	for i := len(t.jumps) - 1; i >= 0; i-- {
+		t.jumps[i].Close()
 	}
+	t.jumps = nil
 }
 
 // check remote service is listening
@@ -280,112 +269,239 @@ func checkDialOnError(remote string, timeout int) error {
 	return err
 }
 
-// NewSSH creates an SSH instance
-func NewSSH(host string, port string, user string, password string, keyfiles []string, timeout int, insecure bool) (*SSH, error) {
-	var auths []ssh.AuthMethod
+// SSHOptions the SSH connection options
+type SSHOptions struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Keyfiles []string
+	// certificates, besides the <keyfile>-cert.pub ones
+	Certificates []string
+	// passphrase of the encrypted keys, none if nil
+	Passphrase *Passphrase
+	Timeout    int
+	Insecure   bool
+	// known_hosts files, default to ~/.ssh/known_hosts
+	KnownHosts []string
+	// pinned SHA256 fingerprints
+	HostKeys []string
+	// trust on first use known_hosts file, disabled if empty
+	TOFU string
+	// hosts to jump through, in order
+	Jumps []*SSHOptions
+}
 
-	log.Debugf("SSH creating a new connection to %s:%s", host, port)
+// Address returns host:port
+func (o *SSHOptions) Address() string {
+	return net.JoinHostPort(o.Host, o.Port)
+}
+
+// String returns user@host:port
+func (o *SSHOptions) String() string {
+	return fmt.Sprintf("%s@%s", o.User, o.Address())
+}
+
+// ParseProxyJump parses a comma separated list of [user@]host[:port]
+func ParseProxyJump(value string) ([]*SSHOptions, error) {
+	var jumps []*SSHOptions
+	for _, hop := range strings.Split(value, ",") {
+		hop = strings.TrimSpace(hop)
+		if len(hop) < 1 {
+			continue
+		}
+		o := &SSHOptions{}
+		idx := strings.LastIndex(hop, "@")
+		if idx >= 0 {
+			o.User = hop[:idx]
+			hop = hop[idx+1:]
+		}
+		host, port, err := net.SplitHostPort(hop)
+		if err != nil {
+			host = strings.Trim(hop, "[]")
+		}
+		if len(host) < 1 {
+			return nil, fmt.Errorf("bad jump host \"%s\"", value)
+		}
+		o.Host = host
+		o.Port = port
+		jumps = append(jumps, o)
+	}
+	return jumps, nil
+}
 
-	if len(host) < 1 {
-		return nil, fmt.Errorf("SSH no host provided")
+// clientConfig validates the options and returns the client config
+// with the report of its auth methods
+func clientConfig(opts *SSHOptions) (*ssh.ClientConfig, *authReport, error) {
+	if len(opts.Host) < 1 {
+		return nil, nil, fmt.Errorf("SSH no host provided")
 	}
-	if len(port) < 1 {
-		port = "22"
+	if len(opts.Port) < 1 {
+		opts.Port = "22"
 	}
-	if len(user) < 1 {
-		return nil, fmt.Errorf("SSH no user provided")
+	if len(opts.User) < 1 {
+		return nil, nil, fmt.Errorf("SSH no user provided")
 	}
 
-	// add password as auth method
-	if len(password) > 1 {
-		auths = append(auths, ssh.Password(password))
-		log.Info("SSH password auth method added")
-	} else {
-		log.Debug("SSH no password provided")
+	auths, report := authMethods(opts)
+	if len(auths) < 1 {
+		return nil, nil, &AuthError{Host: opts.String(), Methods: report.methods()}
 	}
+	log.Debugf("SSH %d auth method(s) for %s", len(auths), opts.String())
 
-	// add default keys
-	if len(keyfiles) < 1 {
-		for _, name := range defaultKeys {
-			keyfile := filepath.Join(os.Getenv("HOME"), ".ssh", name)
-			keyfiles = append(keyfiles, keyfile)
-		}
+	kn, algos, err := hostKeyCallback(opts)
+	if err != nil {
+		log.Debug("SSH knownhost failed: ", err)
+		return nil, nil, err
 	}
 
-	// add keyfile as auth method
-	if len(keyfiles) > 0 {
-		for _, keyfile := range keyfiles {
-			if strings.HasPrefix(keyfile, "~/") {
-				// handle tild
-				keyfile = filepath.Join(os.Getenv("HOME"), keyfile[2:])
-			}
+	config := &ssh.ClientConfig{
+		User:              opts.User,
+		Auth:              auths,
+		HostKeyCallback:   kn,
+		HostKeyAlgorithms: algos,
+		Timeout:           time.Duration(opts.Timeout) * time.Second,
+	}
+	config.SetDefaults()
+	return config, report, nil
+}
 
-			log.Debugf("SSH keyfile: %s", keyfile)
+// dialVia opens an SSH connection tunneled through another client
+func dialVia(via *ssh.Client, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	type result struct {
+		client *ssh.Client
+		err    error
+	}
 
-			if fileExists(keyfile) {
-				log.Debugf("SSH loading keyfile from %s", keyfile)
-				m := loadKeyfile(keyfile)
-				if m != nil {
-					auths = append(auths, m)
-				}
-			} else {
-				log.Debugf("SSH keyfile does not exist: %s", keyfile)
-			}
+	conn, err := via.Dial(protocol, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// the tunneled connection has no deadline support
+	ch := make(chan result, 1)
+	go func() {
+		c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+		if err != nil {
+			ch <- result{nil, err}
+			return
 		}
-	} else {
-		log.Debug("SSH no keyfile found")
+		ch <- result{ssh.NewClient(c, chans, reqs), nil}
+	}()
+
+	// no timeout waits for the handshake forever
+	var timeout <-chan time.Time
+	if config.Timeout > 0 {
+		timer := time.NewTimer(config.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
 	}
 
-	// add agent as auth method
-	a := loadAgent()
-	if a != nil {
-		log.Debug("SSH adding agent as auth method")
-		auths = append(auths, a)
+	select {
+	case res := <-ch:
+		if res.err != nil {
+			conn.Close()
+		}
+		return res.client, res.err
+	case <-timeout:
+		conn.Close()
+		return nil, fmt.Errorf("SSH handshake with %s timed out", addr)
 	}
+}
 
-	var kn ssh.HostKeyCallback
-	if insecure {
-		log.Debug("SSH insecure knownhost")
-		kn = ssh.InsecureIgnoreHostKey()
-	} else {
+// connect dials the jump hosts and then the host
+func (t *SSH) connect(hops []*SSHOptions, configs []*ssh.ClientConfig, reports []*authReport) error {
+	var via *ssh.Client
+	last := len(hops) - 1
+	for i, hop := range hops {
+		var c *ssh.Client
 		var err error
-		kn, err = checkKnownHosts()
+		reports[i].reset()
+		if via == nil {
+			log.Debugf("SSH connecting to %s", hop.String())
+			c, err = ssh.Dial(protocol, hop.Address(), configs[i])
+		} else {
+			log.Debugf("SSH connecting to %s through %s", hop.String(), hops[i-1].String())
+			c, err = dialVia(via, hop.Address(), configs[i])
+		}
+		if isAuthError(err) {
+			log.Debug(err)
+			err = &AuthError{Host: hop.String(), Methods: reports[i].methods()}
+		}
 		if err != nil {
-			log.Debug("SSH knownhost failed: ", err)
-			return nil, err
+			if i != last {
+				return fmt.Errorf("jump host %s: %w", hop.String(), err)
+			}
+			return err
 		}
+		if i != last {
+			t.jumps = append(t.jumps, c)
+		}
+		via = c
 	}
+	t.client = via
+	return nil
+}
 
-	if len(auths) < 1 {
-		return nil, fmt.Errorf("SSH no auth method found")
+// NewSSH creates an SSH instance
+func NewSSH(opts *SSHOptions) (*SSH, error) {
+	log.Debugf("SSH creating a new connection to %s:%s", opts.Host, opts.Port)
+
+	// jump hosts inherit the timeout and the host key checking,
+	// except the pinned keys
+	var hops []*SSHOptions
+	var configs []*ssh.ClientConfig
+	var reports []*authReport
+	for _, jump := range opts.Jumps {
+		hop := *jump
+		hop.Timeout = opts.Timeout
+		hop.Insecure = opts.Insecure
+		hop.TOFU = opts.TOFU
+		if len(hop.KnownHosts) < 1 {
+			hop.KnownHosts = opts.KnownHosts
+		}
+		config, report, err := clientConfig(&hop)
+		if err != nil {
+			return nil, fmt.Errorf("jump host %s: %w", jump.Host, err)
+		}
+		hops = append(hops, &hop)
+		configs = append(configs, config)
+		reports = append(reports, report)
 	}
-	log.Debugf("SSH %d auth method(s): %#v", len(auths), auths)
-
-	t := &SSH{}
-	t.config = &ssh.ClientConfig{
-		User:            user,
-		Auth:            auths,
-		HostKeyCallback: kn,
-		Timeout:         time.Duration(timeout) * time.Second,
+	config, report, err := clientConfig(opts)
+	if err != nil {
+		return nil, err
 	}
-	t.config.SetDefaults()
+	hops = append(hops, opts)
+	configs = append(configs, config)
+	reports = append(reports, report)
 
-	var c *ssh.Client
-	var err error
-	remote := fmt.Sprintf("%s:%s", host, port)
+	t := &SSH{
+		config: config,
+	}
+	remote := opts.Address()
 	for i := 0; i < connRetry; i++ {
-		log.Debugf("SSH connecting to %s@%s port %s (%d/%d)", user, host, port, i+1, connRetry)
-		c, err = ssh.Dial(protocol, remote, t.config)
+		log.Debugf("SSH connecting to %s (%d/%d)", opts.String(), i+1, connRetry)
+		err = t.connect(hops, configs, reports)
 		if err == nil {
 			break
 		}
-		// connection error
-		dialErr := checkDialOnError(remote, timeout)
-		if dialErr != nil {
+		t.Close()
+
+		// retrying does not change the host key nor the credentials
+		var keyErr *HostKeyError
+		var authErr *AuthError
+		if errors.As(err, &keyErr) || errors.As(err, &authErr) {
+			return nil, fmt.Errorf("SSH connection error: %w", err)
+		}
+
+		if len(opts.Jumps) > 0 {
+			err = fmt.Errorf("SSH connection error: %w", err)
+		} else if checkDialOnError(remote, opts.Timeout) != nil {
 			// host is NOT reachable
-			err = fmt.Errorf("SSH connection error: %s", err.Error())
+			err = fmt.Errorf("SSH connection error: %w", err)
 		} else {
-			err = fmt.Errorf("SSH connection error but host is reachable: %s", err.Error())
+			err = fmt.Errorf("SSH connection error but host is reachable: %w", err)
 		}
 		log.Debug(err)
 		time.Sleep(time.Duration(retrySleep) * time.Second)
@@ -395,7 +511,6 @@ func NewSSH(host string, port string, user string, password string, keyfiles []s
 		return nil, err
 	}
 
-	log.Debugf("SSH connected to %s@%s port %s", user, host, port)
-	t.client = c
+	log.Debugf("SSH connected to %s", opts.String())
 	return t, nil
 }
@@ -0,0 +1,58 @@
+// Copyright (c) 2021 deadc0de6
+
+package transport
+
+import (
+	"testing"
+)
+
+func TestParseProxyJump(t *testing.T) {
+	type hop struct {
+		user string
+		host string
+		port string
+	}
+	tests := []struct {
+		value string
+		want  []hop
+		ok    bool
+	}{
+		{"bastion", []hop{{"", "bastion", ""}}, true},
+		{"admin@bastion", []hop{{"admin", "bastion", ""}}, true},
+		{"admin@bastion:2222", []hop{{"admin", "bastion", "2222"}}, true},
+		{"bastion:2222", []hop{{"", "bastion", "2222"}}, true},
+		{"user@corp@bastion", []hop{{"user@corp", "bastion", ""}}, true},
+		{"[::1]:2222", []hop{{"", "::1", "2222"}}, true},
+		{"admin@[fe80::1]", []hop{{"admin", "fe80::1", ""}}, true},
+		{
+			"a@one:22, two ,b@three:2222",
+			[]hop{{"a", "one", "22"}, {"", "two", ""}, {"b", "three", "2222"}},
+			true,
+		},
+		{"one,,two", []hop{{"", "one", ""}, {"", "two", ""}}, true},
+		{"", nil, true},
+		{"admin@", nil, false},
+		{"one,admin@", nil, false},
+		{":2222", nil, false},
+	}
+	for _, tt := range tests {
+		jumps, err := ParseProxyJump(tt.value)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseProxyJump(%q) error = %v, want ok %t", tt.value, err, tt.ok)
+			continue
+		}
+		if !tt.ok {
+			continue
+		}
+		if len(jumps) != len(tt.want) {
+			t.Errorf("ParseProxyJump(%q) = %d hops, want %d", tt.value, len(jumps), len(tt.want))
+			continue
+		}
+		for i, j := range jumps {
+			got := hop{j.User, j.Host, j.Port}
+			if got != tt.want[i] {
+				t.Errorf("ParseProxyJump(%q) hop %d = %+v, want %+v", tt.value, i, got, tt.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,396 @@
+// Copyright (c) 2021 deadc0de6
+
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+const (
+	certSuffix        = "-cert.pub"
+	passphraseTimeout = 30
+)
+
+var (
+	defaultKeys = []string{
+		"id_rsa",
+		"id_ed25519",
+	}
+)
+
+// Passphrase the source of the passphrase of the encrypted keys,
+// either an env variable, a file or a command printing it
+type Passphrase struct {
+	Env     string
+	File    string
+	Command string
+	value   []byte
+	mut     sync.Mutex
+}
+
+// Get returns the passphrase, the command is run only once
+func (p *Passphrase) Get() ([]byte, error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if p.value != nil {
+		return p.value, nil
+	}
+
+	var value string
+	switch {
+	case len(p.Env) > 0:
+		value = os.Getenv(p.Env)
+		if len(value) < 1 {
+			return nil, fmt.Errorf("env variable %s is empty", p.Env)
+		}
+	case len(p.File) > 0:
+		b, err := os.ReadFile(ExpandPath(p.File, "", ""))
+		if err != nil {
+			return nil, err
+		}
+		value = strings.TrimRight(string(b), "\r\n")
+	case len(p.Command) > 0:
+		ctx, cancel := context.WithTimeout(context.Background(), passphraseTimeout*time.Second)
+		defer cancel()
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", p.Command)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("passphrase command failed: %v %s", err, strings.TrimSpace(stderr.String()))
+		}
+		value = strings.TrimRight(string(out), "\r\n")
+	default:
+		return nil, fmt.Errorf("no passphrase configured")
+	}
+	p.value = []byte(value)
+	return p.value, nil
+}
+
+// AuthError an SSH authentication failure with
+// the outcome of each auth method
+type AuthError struct {
+	Host    string
+	Methods []string
+}
+
+// Error returns the error string
+func (e *AuthError) Error() string {
+	if len(e.Methods) < 1 {
+		return fmt.Sprintf("authentication failed for %s: no auth method", e.Host)
+	}
+	return fmt.Sprintf("authentication failed for %s: %s", e.Host, strings.Join(e.Methods, "; "))
+}
+
+// an auth method offered to the server
+type authAttempt struct {
+	name      string
+	publickey bool
+	// why it was not offered, empty if offered
+	skipped string
+	// set when the server accepted the password or key
+	status string
+}
+
+// authReport records the outcome of the auth methods of a host
+type authReport struct {
+	attempts  []*authAttempt
+	publickey bool
+}
+
+func (r *authReport) add(name string, publickey bool, skipped string) *authAttempt {
+	a := &authAttempt{
+		name:      name,
+		publickey: publickey,
+		skipped:   skipped,
+	}
+	r.attempts = append(r.attempts, a)
+	if len(skipped) > 0 {
+		log.Debugf("SSH %s skipped: %s", name, skipped)
+	}
+	return a
+}
+
+// reset forgets the outcome of the previous connection
+func (r *authReport) reset() {
+	r.publickey = false
+	for _, a := range r.attempts {
+		a.status = ""
+	}
+}
+
+// methods returns the outcome of each auth method
+func (r *authReport) methods() []string {
+	var methods []string
+	for _, a := range r.attempts {
+		status := a.status
+		switch {
+		case len(a.skipped) > 0:
+			status = a.skipped
+		case len(status) > 0:
+		case a.publickey && r.publickey:
+			status = "rejected"
+		default:
+			status = "not tried"
+		}
+		methods = append(methods, fmt.Sprintf("%s: %s", a.name, status))
+	}
+	return methods
+}
+
+// trackedSigner a signer recording the server accepted its key,
+// keys are only signed with once accepted by the server
+type trackedSigner struct {
+	ssh.AlgorithmSigner
+	attempt *authAttempt
+}
+
+// Sign signs
+func (s *trackedSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	s.attempt.status = "accepted"
+	return s.AlgorithmSigner.Sign(rand, data)
+}
+
+// SignWithAlgorithm signs with an algorithm
+func (s *trackedSigner) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	s.attempt.status = "accepted"
+	return s.AlgorithmSigner.SignWithAlgorithm(rand, data, algorithm)
+}
+
+// trackedMultiSigner a tracked signer restricted to some algorithms
+type trackedMultiSigner struct {
+	*trackedSigner
+	algorithms []string
+}
+
+// Algorithms returns the supported algorithms
+func (s *trackedMultiSigner) Algorithms() []string {
+	return s.algorithms
+}
+
+// track wraps a signer to record its outcome, the
+// algorithm interfaces are kept for the rsa-sha2 signatures
+func track(signer ssh.Signer, attempt *authAttempt) ssh.Signer {
+	as, ok := signer.(ssh.AlgorithmSigner)
+	if !ok {
+		return signer
+	}
+	t := &trackedSigner{
+		AlgorithmSigner: as,
+		attempt:         attempt,
+	}
+	ms, ok := signer.(ssh.MultiAlgorithmSigner)
+	if ok {
+		return &trackedMultiSigner{
+			trackedSigner: t,
+			algorithms:    ms.Algorithms(),
+		}
+	}
+	return t
+}
+
+func keyName(kind string, key ssh.PublicKey) string {
+	return fmt.Sprintf("%s %s %s", kind, key.Type(), ssh.FingerprintSHA256(key))
+}
+
+func loadAgent() []ssh.Signer {
+	path := os.Getenv("SSH_AUTH_SOCK")
+	if len(path) < 1 {
+		log.Debug("SSH no auth socket found")
+		return nil
+	}
+	sock, err := net.Dial("unix", path)
+	if err != nil {
+		log.Debug(err)
+		return nil
+	}
+
+	a := agent.NewClient(sock)
+	log.Debug("SSH agent socket found")
+
+	signers, err := a.Signers()
+	if err != nil {
+		log.Debug(err)
+		return nil
+	}
+	return signers
+}
+
+// loadCertificate returns the user certificate of a key,
+// nil if it is not for this key
+func loadCertificate(path string, signer ssh.Signer) (*ssh.Certificate, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(b)
+	if err != nil {
+		return nil, err
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("not a certificate")
+	}
+	if !bytes.Equal(cert.Key.Marshal(), signer.PublicKey().Marshal()) {
+		return nil, nil
+	}
+	if cert.CertType != ssh.UserCert {
+		return nil, fmt.Errorf("not a user certificate")
+	}
+	now := uint64(time.Now().Unix())
+	if now < cert.ValidAfter {
+		return nil, fmt.Errorf("not yet valid")
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity && now >= cert.ValidBefore {
+		return nil, fmt.Errorf("expired")
+	}
+	return cert, nil
+}
+
+// loadKeyfile returns the signers of a key, its certificates first,
+// the encrypted keys are decrypted with the passphrase
+func loadKeyfile(path string, certs []string, pass *Passphrase, report *authReport) []ssh.Signer {
+	log.Debugf("SSH loading key from \"%s\"", path)
+	name := fmt.Sprintf("key %s", path)
+	key, err := os.ReadFile(path)
+	if err != nil {
+		report.add(name, true, err.Error())
+		return nil
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if pass == nil {
+			report.add(name, true, "encrypted and no passphrase configured")
+			return nil
+		}
+		p, perr := pass.Get()
+		if perr != nil {
+			report.add(name, true, fmt.Sprintf("encrypted and no passphrase: %v", perr))
+			return nil
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, p)
+		if err != nil {
+			report.add(name, true, fmt.Sprintf("cannot decrypt: %v", err))
+			return nil
+		}
+	} else if err != nil {
+		report.add(name, true, fmt.Sprintf("cannot parse: %v", err))
+		return nil
+	}
+
+	var signers []ssh.Signer
+	certs = append(append([]string{}, certs...), path+certSuffix)
+	for _, certPath := range certs {
+		if !fileExists(certPath) {
+			continue
+		}
+		certName := fmt.Sprintf("certificate %s", certPath)
+		cert, err := loadCertificate(certPath, signer)
+		if err != nil {
+			report.add(certName, true, err.Error())
+			continue
+		}
+		if cert == nil {
+			log.Debugf("SSH certificate %s is not for key %s", certPath, path)
+			continue
+		}
+		certSigner, err := ssh.NewCertSigner(cert, signer)
+		if err != nil {
+			report.add(certName, true, err.Error())
+			continue
+		}
+		log.Debugf("SSH certificate \"%s\" (id %s) loaded", certPath, cert.KeyId)
+		attempt := report.add(fmt.Sprintf("%s (id %s)", certName, cert.KeyId), true, "")
+		signers = append(signers, track(certSigner, attempt))
+	}
+
+	attempt := report.add(keyName(name, signer.PublicKey()), true, "")
+	signers = append(signers, track(signer, attempt))
+	return signers
+}
+
+// authMethods returns the password and public keys auth methods,
+// all the keys are in a single method as each method is tried once
+func authMethods(opts *SSHOptions) ([]ssh.AuthMethod, *authReport) {
+	var auths []ssh.AuthMethod
+	report := &authReport{}
+
+	// add password as auth method
+	if len(opts.Password) > 1 {
+		attempt := report.add("password", false, "")
+		auths = append(auths, ssh.PasswordCallback(func() (string, error) {
+			attempt.status = "rejected"
+			return opts.Password, nil
+		}))
+		log.Info("SSH password auth method added")
+	} else {
+		log.Debug("SSH no password provided")
+	}
+
+	// add default keys, only the configured ones are reported missing
+	keyfiles := opts.Keyfiles
+	defaults := len(keyfiles) < 1
+	if defaults {
+		for _, name := range defaultKeys {
+			keyfile := filepath.Join(os.Getenv("HOME"), ".ssh", name)
+			keyfiles = append(keyfiles, keyfile)
+		}
+	}
+
+	// add keyfiles as auth method
+	var signers []ssh.Signer
+	for _, keyfile := range keyfiles {
+		if strings.HasPrefix(keyfile, "~/") {
+			// handle tild
+			keyfile = filepath.Join(os.Getenv("HOME"), keyfile[2:])
+		}
+
+		log.Debugf("SSH keyfile: %s", keyfile)
+
+		if !fileExists(keyfile) {
+			log.Debugf("SSH keyfile does not exist: %s", keyfile)
+			if !defaults {
+				report.add(fmt.Sprintf("key %s", keyfile), true, "not found")
+			}
+			continue
+		}
+		signers = append(signers, loadKeyfile(keyfile, opts.Certificates, opts.Passphrase, report)...)
+	}
+
+	// add agent keys
+	for _, signer := range loadAgent() {
+		attempt := report.add(keyName("agent key", signer.PublicKey()), true, "")
+		signers = append(signers, track(signer, attempt))
+	}
+
+	if len(signers) > 0 {
+		log.Debugf("SSH %d public key(s) added", len(signers))
+		auths = append(auths, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			report.publickey = true
+			return signers, nil
+		}))
+	}
+	return auths, report
+}
+
+// isAuthError returns true if the error is an authentication failure
+func isAuthError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "ssh: unable to authenticate")
+}
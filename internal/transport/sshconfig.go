@@ -0,0 +1,177 @@
+// Copyright (c) 2021 deadc0de6
+
+package transport
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	maxIncludeDepth = 16
+)
+
+// a Host block of the OpenSSH client config
+type sshBlock struct {
+	patterns []string
+	options  [][2]string
+}
+
+// SSHConfig an OpenSSH client config (ssh_config(5)),
+// only the Host blocks and "Match all" are supported
+type SSHConfig struct {
+	blocks []*sshBlock
+}
+
+// match returns true if the host matches the block patterns
+func (b *sshBlock) match(host string) bool {
+	matched := false
+	for _, p := range b.patterns {
+		negated := strings.HasPrefix(p, "!")
+		ok, _ := path.Match(strings.TrimPrefix(p, "!"), host)
+		if ok && negated {
+			return false
+		}
+		if ok {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// GetAll returns all the values of a keyword for a host
+func (c *SSHConfig) GetAll(host string, key string) []string {
+	if c == nil {
+		return nil
+	}
+	key = strings.ToLower(key)
+	var values []string
+	for _, b := range c.blocks {
+		if !b.match(host) {
+			continue
+		}
+		for _, opt := range b.options {
+			if opt[0] == key {
+				values = append(values, opt[1])
+			}
+		}
+	}
+	return values
+}
+
+// Get returns the first value of a keyword for a host
+func (c *SSHConfig) Get(host string, key string) string {
+	values := c.GetAll(host, key)
+	if len(values) < 1 {
+		return ""
+	}
+	return values[0]
+}
+
+// ExpandPath expands ~ and the %d (home), %u (local user),
+// %h (host) and %r (remote user) tokens
+func ExpandPath(p string, host string, user string) string {
+	home := os.Getenv("HOME")
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		p = filepath.Join(home, p[1:])
+	}
+	r := strings.NewReplacer("%d", home, "%u", os.Getenv("USER"), "%h", host, "%r", user, "%%", "%")
+	return r.Replace(p)
+}
+
+// splitLine splits "keyword value" and "keyword=value"
+// and removes the quotes around the value
+func splitLine(line string) (string, string) {
+	idx := strings.IndexAny(line, " \t=")
+	if idx < 0 {
+		return strings.ToLower(line), ""
+	}
+	key := strings.ToLower(line[:idx])
+	value := strings.TrimSpace(line[idx:])
+	value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+	value = strings.Trim(value, "\"")
+	return key, value
+}
+
+func (c *SSHConfig) parse(p string, cur *sshBlock, depth int) (*sshBlock, error) {
+	if depth > maxIncludeDepth {
+		return nil, fmt.Errorf("too many nested includes in %s", p)
+	}
+	log.Debugf("SSH reading config from \"%s\"", p)
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) < 1 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value := splitLine(line)
+		switch key {
+		case "host":
+			cur = &sshBlock{
+				patterns: strings.Fields(value),
+			}
+			c.blocks = append(c.blocks, cur)
+		case "match":
+			cur = &sshBlock{}
+			if strings.ToLower(value) == "all" {
+				cur.patterns = []string{"*"}
+			} else {
+				log.Debugf("SSH config \"Match %s\" is not supported", value)
+			}
+			c.blocks = append(c.blocks, cur)
+		case "include":
+			for _, inc := range strings.Fields(value) {
+				inc = ExpandPath(inc, "", "")
+				if !filepath.IsAbs(inc) {
+					inc = filepath.Join(os.Getenv("HOME"), ".ssh", inc)
+				}
+				paths, _ := filepath.Glob(inc)
+				for _, incPath := range paths {
+					// like OpenSSH, back to the enclosing
+					// block once the file is included
+					_, err = c.parse(incPath, cur, depth+1)
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+		default:
+			cur.options = append(cur.options, [2]string{key, value})
+		}
+	}
+	return cur, scanner.Err()
+}
+
+// LoadSSHConfig parses an OpenSSH client config,
+// a missing file is an empty config
+func LoadSSHConfig(p string) (*SSHConfig, error) {
+	c := &SSHConfig{}
+	_, err := os.Stat(p)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+
+	// options before the first Host apply to all hosts
+	cur := &sshBlock{
+		patterns: []string{"*"},
+	}
+	c.blocks = append(c.blocks, cur)
+	_, err = c.parse(p, cur, 0)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2021 deadc0de6
+
+package transport
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSSHBlockMatch(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		host     string
+		want     bool
+	}{
+		{[]string{"*"}, "web1", true},
+		{[]string{"web1"}, "web1", true},
+		{[]string{"web1"}, "web2", false},
+		{[]string{"web?"}, "web2", true},
+		{[]string{"web?"}, "web10", false},
+		{[]string{"*.example.com"}, "web.example.com", true},
+		{[]string{"*.example.com"}, "example.com", false},
+		{[]string{"db1", "web*"}, "web1", true},
+		// a negation only excludes
+		{[]string{"!web1"}, "web2", false},
+		{[]string{"web*", "!web1"}, "web1", false},
+		{[]string{"web*", "!web1"}, "web2", true},
+		{[]string{"!web1", "web*"}, "web1", false},
+		{[]string{"!*.internal", "*"}, "db.internal", false},
+		{nil, "web1", false},
+	}
+	for _, tt := range tests {
+		b := &sshBlock{patterns: tt.patterns}
+		got := b.match(tt.host)
+		if got != tt.want {
+			t.Errorf("%v match %q = %t, want %t", tt.patterns, tt.host, got, tt.want)
+		}
+	}
+}
+
+func writeFile(t *testing.T, p string, content string) {
+	t.Helper()
+	err := os.WriteFile(p, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadSSHConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	sshDir := filepath.Join(home, ".ssh")
+	err := os.MkdirAll(filepath.Join(sshDir, "conf.d"), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, filepath.Join(sshDir, "config"), `# global
+Port 2222
+Include conf.d/*.conf
+User globaluser
+
+Host web* !web3
+  User admin
+  IdentityFile ~/.ssh/web
+
+Host=db1
+  HostName "10.0.0.1"
+  Include extra
+
+Match all
+  IdentityFile ~/.ssh/default
+`)
+	writeFile(t, filepath.Join(sshDir, "conf.d", "a.conf"), `Host bastion
+  User jump
+`)
+	writeFile(t, filepath.Join(sshDir, "extra"), `User dba
+`)
+
+	c, err := LoadSSHConfig(filepath.Join(sshDir, "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host string
+		key  string
+		want []string
+	}{
+		{"web1", "port", []string{"2222"}},
+		{"web1", "User", []string{"globaluser", "admin"}},
+		{"web1", "identityfile", []string{"~/.ssh/web", "~/.ssh/default"}},
+		{"web3", "user", []string{"globaluser"}},
+		{"web3", "identityfile", []string{"~/.ssh/default"}},
+		{"db1", "hostname", []string{"10.0.0.1"}},
+		// included within the Host block
+		{"db1", "user", []string{"globaluser", "dba"}},
+		// included at the top level
+		{"bastion", "user", []string{"globaluser", "jump"}},
+		// after the include, back to the global block
+		{"other", "user", []string{"globaluser"}},
+	}
+	for _, tt := range tests {
+		got := c.GetAll(tt.host, tt.key)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetAll(%q, %q) = %q, want %q", tt.host, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestLoadSSHConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	// a missing config is an empty one
+	c, err := LoadSSHConfig(filepath.Join(dir, "none"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Get("web1", "user"); got != "" {
+		t.Errorf("Get on an empty config = %q", got)
+	}
+
+	// an include loop
+	loop := filepath.Join(dir, "loop")
+	writeFile(t, loop, "Include "+loop+"\n")
+	_, err = LoadSSHConfig(loop)
+	if err == nil {
+		t.Errorf("no error on an include loop")
+	}
+}
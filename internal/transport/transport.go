@@ -2,10 +2,51 @@
 
 package transport
 
-// Transport the interface to transports
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Result the outcome of a command
+type Result struct {
+	Stdout string
+	Stderr string
+	// -1 if the command did not exit
+	ExitCode int
+	Duration time.Duration
+	// the command could not run or its outcome is
+	// unknown (connection lost, timeout, ...)
+	TransportError bool
+}
+
+// ExitError a command exiting with a non-zero code
+type ExitError struct {
+	Code int
+}
+
+// Error returns the error string
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exit code: %d", e.Code)
+}
+
+// newResult returns the result of a command started at start
+func newResult(stdout string, stderr string, start time.Time) *Result {
+	r := &Result{
+		Stdout:   stdout,
+		Stderr:   stderr,
+		ExitCode: -1,
+		Duration: time.Since(start),
+	}
+	return r
+}
+
+// Transport the interface to transports, the result of
+// a command is never nil, the error is set if it failed
 type Transport interface {
-	Execute(string) (string, string, error)
-	Copy(string, string, string) error
-	Mkdir(string) error
+	Execute(context.Context, string) (*Result, error)
+	ExecuteWithInput(context.Context, string, string) (*Result, error)
+	Copy(context.Context, string, string, string) error
+	Mkdir(context.Context, string) error
 	Close()
 }